@@ -0,0 +1,98 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_auth
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	// TagForSubject is the inbound tag set to the subject of the authenticated Principal.
+	TagForSubject = "auth.subject"
+	// TagForMethod is the inbound tag set to the method the Principal was authenticated with.
+	TagForMethod = "auth.method"
+)
+
+// AuthFunc is the pluggable function that performs authentication of requests.
+//
+// It returns the context the handler is called with, which should carry the authenticated Principal (see `ToContext`).
+// Returning an error rejects the request. Returning a nil context and no error lets the request through with its
+// context unchanged.
+type AuthFunc func(req *http.Request) (context.Context, error)
+
+// AuthFuncOverride is implemented by handlers that authenticate their requests differently.
+//
+// If the handler wrapped by the Middleware implements it, `AuthFuncOverride` is called instead of the AuthFunc, and its
+// results are handled the same way.
+type AuthFuncOverride interface {
+	AuthFuncOverride(req *http.Request) (context.Context, error)
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Subject identifies the caller, e.g. a user name or the subject of a token.
+	Subject string
+	// Method is how the caller was authenticated, e.g. "bearer", "basic" or "api_key".
+	Method string
+	// Attributes are any other details of the caller, e.g. claims of a token.
+	Attributes map[string]interface{}
+}
+
+type ctxMarker struct{}
+
+var principalKey = &ctxMarker{}
+
+// Extract returns the Principal authenticated for the request, if any.
+func Extract(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey).(*Principal)
+	return p, ok && p != nil
+}
+
+// ToContext returns a context carrying the authenticated Principal.
+func ToContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// Error is an authentication error with details of the response to send.
+type Error struct {
+	// Code is the status code of the response, usually 401 (Unauthorized) or 403 (Forbidden).
+	Code int
+	// Challenges are the values of the `WWW-Authenticate` headers of the response.
+	Challenges []string
+	// Message describes the error, it isn't sent to the client by the DefaultErrorHandler.
+	Message string
+
+	missingCredentials bool
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unauthenticated returns an error rejecting requests with a 401 (Unauthorized) status code.
+func Unauthenticated(message string, challenges ...string) *Error {
+	return &Error{Code: http.StatusUnauthorized, Challenges: challenges, Message: message}
+}
+
+// PermissionDenied returns an error rejecting requests with a 403 (Forbidden) status code.
+func PermissionDenied(message string) *Error {
+	return &Error{Code: http.StatusForbidden, Message: message}
+}
+
+func missingCredentials(challenge string) *Error {
+	return &Error{
+		Code:               http.StatusUnauthorized,
+		Challenges:         []string{challenge},
+		Message:            "missing credentials",
+		missingCredentials: true,
+	}
+}
+
+// IsMissingCredentials reports whether the error was returned by a built in AuthFunc because the request didn't have
+// the credentials it looks for.
+func IsMissingCredentials(err error) bool {
+	authErr, ok := err.(*Error)
+	return ok && authErr.missingCredentials
+}
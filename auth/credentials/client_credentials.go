@@ -0,0 +1,207 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	defaultClientCredentialsOptions = &clientCredentialsOptions{
+		scopes:         nil,
+		endpointParams: url.Values{},
+		expiryMargin:   time.Minute,
+		fetchTimeout:   10 * time.Second,
+	}
+)
+
+type clientCredentialsOptions struct {
+	scopes         []string
+	endpointParams url.Values
+	expiryMargin   time.Duration
+	fetchTimeout   time.Duration
+}
+
+// ClientCredentialsOption customizes a ClientCredentials TokenSource.
+type ClientCredentialsOption func(*clientCredentialsOptions)
+
+// WithScopes sets the scopes requested for tokens.
+func WithScopes(scopes ...string) ClientCredentialsOption {
+	return func(o *clientCredentialsOptions) {
+		o.scopes = scopes
+	}
+}
+
+// WithEndpointParams adds parameters to the token requests, e.g. the `audience` that some providers need.
+func WithEndpointParams(params url.Values) ClientCredentialsOption {
+	return func(o *clientCredentialsOptions) {
+		for k, v := range params {
+			o.endpointParams[k] = v
+		}
+	}
+}
+
+// WithExpiryMargin sets how long before they expire tokens are replaced. The default is one minute.
+func WithExpiryMargin(margin time.Duration) ClientCredentialsOption {
+	return func(o *clientCredentialsOptions) {
+		o.expiryMargin = margin
+	}
+}
+
+// WithFetchTimeout sets how long requesting a token may take. The default is 10 seconds.
+func WithFetchTimeout(timeout time.Duration) ClientCredentialsOption {
+	return func(o *clientCredentialsOptions) {
+		o.fetchTimeout = timeout
+	}
+}
+
+// ClientCredentials is a TokenSource getting tokens from an OAuth2 token endpoint with the client credentials grant.
+type ClientCredentials struct {
+	client       *http.Client
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	opts         *clientCredentialsOptions
+
+	mu       sync.Mutex
+	token    *Token
+	fetching *tokenFetch
+}
+
+// tokenFetch is a request for a token in flight, shared by all the callers waiting for it.
+type tokenFetch struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// NewClientCredentials returns a TokenSource getting tokens from the token URL with the client, authenticated with the
+// client ID and secret.
+//
+// The client must not be wrapped with the Tripperware using this TokenSource.
+func NewClientCredentials(client *http.Client, tokenURL string, clientID string, clientSecret string, opts ...ClientCredentialsOption) *ClientCredentials {
+	o := &clientCredentialsOptions{}
+	*o = *defaultClientCredentialsOptions
+	o.endpointParams = url.Values{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &ClientCredentials{client: client, tokenURL: tokenURL, clientID: clientID, clientSecret: clientSecret, opts: o}
+}
+
+func (c *ClientCredentials) Token(ctx context.Context) (*Token, error) {
+	return c.get(ctx, nil)
+}
+
+// Refresh returns a new token, unless the rejected token was already replaced.
+func (c *ClientCredentials) Refresh(ctx context.Context, rejected *Token) (*Token, error) {
+	return c.get(ctx, rejected)
+}
+
+func (c *ClientCredentials) get(ctx context.Context, rejected *Token) (*Token, error) {
+	c.mu.Lock()
+	if c.token != nil && (rejected == nil || c.token.Value != rejected.Value) && c.fresh(c.token) {
+		token := c.token
+		c.mu.Unlock()
+		return token, nil
+	}
+	if rejected != nil && c.token != nil && c.token.Value == rejected.Value {
+		c.token = nil // so that callers don't keep getting it while a new one is fetched
+	}
+	f := c.fetchLocked()
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.token, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *ClientCredentials) fresh(token *Token) bool {
+	return token.Expiry.IsZero() || time.Now().Add(c.opts.expiryMargin).Before(token.Expiry)
+}
+
+// fetchLocked starts requesting a token, unless that is in progress already.
+func (c *ClientCredentials) fetchLocked() *tokenFetch {
+	if c.fetching != nil {
+		return c.fetching
+	}
+	f := &tokenFetch{done: make(chan struct{})}
+	c.fetching = f
+	go func() {
+		f.token, f.err = c.fetch()
+		c.mu.Lock()
+		if f.err == nil {
+			c.token = f.token
+		}
+		c.fetching = nil
+		c.mu.Unlock()
+		close(f.done)
+	}()
+	return f
+}
+
+func (c *ClientCredentials) fetch() (*Token, error) {
+	// Not tied to the request that happened to need the token, as others may be waiting for it too.
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.fetchTimeout)
+	defer cancel()
+	form := url.Values{}
+	for k, v := range c.opts.endpointParams {
+		form[k] = v
+	}
+	form.Set("grant_type", "client_credentials")
+	if len(c.opts.scopes) > 0 {
+		form.Set("scope", strings.Join(c.opts.scopes, " "))
+	}
+	req, err := http.NewRequest("POST", c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+	requestTime := time.Now()
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body := &struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}{}
+	decodeErr := json.NewDecoder(resp.Body).Decode(body)
+	if resp.StatusCode != http.StatusOK {
+		if body.Error != "" {
+			return nil, fmt.Errorf("credentials: token request failed with %v: %v", body.Error, body.ErrorDescription)
+		}
+		return nil, fmt.Errorf("credentials: token request failed with status %v", resp.StatusCode)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("credentials: malformed token response: %v", decodeErr)
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("credentials: token response without a token")
+	}
+	token := &Token{Type: body.TokenType, Value: body.AccessToken}
+	if strings.EqualFold(token.Type, "bearer") || token.Type == "" {
+		token.Type = "Bearer" // some servers send it in lower case, which not all others accept
+	}
+	if body.ExpiresIn > 0 {
+		token.Expiry = requestTime.Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
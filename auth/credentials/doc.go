@@ -0,0 +1,22 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_credentials` sets credentials on outbound requests, for service-to-service calls.
+
+The Tripperware sets the `Authorization` header of requests to a token from a `TokenSource`, which can differ for each
+service called (as named by the `http.call.service` tag of `http_ctxtags`), so that a single client can call multiple
+APIs. Requests that already have an `Authorization` header are left alone.
+
+If the server rejects a token with a 401 (Unauthorized) status code and the TokenSource is a `Refresher`, a new token is
+requested and the request is sent once more with it. Request bodies without `GetBody` are buffered in memory for that.
+
+Token Sources
+
+`StaticToken` always returns the same token, e.g. an API key.
+
+`ClientCredentials` gets tokens from an OAuth2 token endpoint using the client credentials grant (RFC 6749, section
+4.4). Tokens are cached and replaced some time before they expire, and callers needing a new token at the same time
+share a single request for it.
+*/
+package http_credentials
@@ -0,0 +1,201 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_credentials_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/auth/credentials"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// tokenServer is an OAuth2 token endpoint issuing numbered tokens, the latest of which is the only one accepted by
+// the apiHandler.
+type tokenServer struct {
+	*httptest.Server
+	issued    int32
+	expiresIn int
+	delay     time.Duration
+}
+
+func newTokenServer(expiresIn int) *tokenServer {
+	s := &tokenServer{expiresIn: expiresIn}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		time.Sleep(s.delay)
+		user, password, _ := req.BasicAuth()
+		if user != "my-client" || password != "my%2Fsecret" || req.FormValue("grant_type") != "client_credentials" {
+			resp.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(resp).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		token := fmt.Sprintf("token-%d-%s", atomic.AddInt32(&s.issued, 1), strings.Replace(req.FormValue("scope"), " ", "+", -1))
+		resp.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(resp).Encode(map[string]interface{}{
+			"access_token": token,
+			"token_type":   "bearer",
+			"expires_in":   s.expiresIn,
+		})
+	}))
+	return s
+}
+
+func (s *tokenServer) latest() string {
+	return fmt.Sprintf("Bearer token-%d-", atomic.LoadInt32(&s.issued))
+}
+
+// apiHandler accepts the latest token of the token server, and reports back the Authorization and body it got.
+type apiHandler struct {
+	tokens *tokenServer
+}
+
+func (h *apiHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	auth := req.Header.Get("Authorization")
+	body, _ := ioutil.ReadAll(req.Body)
+	resp.Header().Set("x-test-authorization", auth)
+	resp.Header().Set("x-test-body", string(body))
+	if strings.HasPrefix(auth, "Bearer token-") && !strings.HasPrefix(auth, h.tokens.latest()) {
+		resp.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+}
+
+func TestCredentialsSuite(t *testing.T) {
+	tokens := newTokenServer(3600)
+	source := http_credentials.NewClientCredentials(&http.Client{}, tokens.URL, "my-client", "my/secret", http_credentials.WithScopes("read", "write"))
+	s := &CredentialsSuite{
+		tokens: tokens,
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: &apiHandler{tokens},
+			ClientTripperware: []httpwares.Tripperware{
+				func(next http.RoundTripper) http.RoundTripper {
+					return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+						svc := req.URL.Query().Get("service")
+						return http_ctxtags.Tripperware(http_ctxtags.WithServiceName(svc))(next).RoundTrip(req)
+					})
+				},
+				http_credentials.Tripperware(source, http_credentials.WithServiceTokenSource("static", http_credentials.StaticToken("ApiKey", "my-key"))),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type CredentialsSuite struct {
+	*httpwares_testing.WaresTestSuite
+	tokens *tokenServer
+}
+
+func (s *CredentialsSuite) TearDownSuite() {
+	s.tokens.Close()
+	s.WaresTestSuite.TearDownSuite()
+}
+
+func (s *CredentialsSuite) call(method string, service string, body string, headers ...string) *http.Response {
+	req, _ := http.NewRequest(method, "https://something.local/someurl?service="+service, strings.NewReader(body))
+	if body == "" {
+		req.Body = nil
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	return resp
+}
+
+func (s *CredentialsSuite) TestTokenIsCachedAndShared() {
+	issued := atomic.LoadInt32(&s.tokens.issued)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := s.call("GET", "api", "")
+			assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+			assert.Equal(s.T(), s.tokens.latest()+"read+write", resp.Header.Get("x-test-authorization"))
+		}()
+	}
+	wg.Wait()
+	assert.True(s.T(), atomic.LoadInt32(&s.tokens.issued)-issued <= 1, "a single token must be requested at most")
+}
+
+func (s *CredentialsSuite) TestRejectedTokenIsRefreshedOnce() {
+	s.call("GET", "api", "")             // make sure a token is cached
+	atomic.AddInt32(&s.tokens.issued, 1) // which revokes it
+	resp := s.call("POST", "api", "some body")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode, "the request must be retried with a new token")
+	assert.Equal(s.T(), s.tokens.latest()+"read+write", resp.Header.Get("x-test-authorization"))
+	assert.Equal(s.T(), "some body", resp.Header.Get("x-test-body"), "the body must be sent again")
+}
+
+func (s *CredentialsSuite) TestServiceTokenSource() {
+	resp := s.call("GET", "static", "")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), "ApiKey my-key", resp.Header.Get("x-test-authorization"))
+}
+
+func (s *CredentialsSuite) TestExistingAuthorizationIsKept() {
+	resp := s.call("GET", "api", "", "Authorization", "Basic dXNlcjpwYXNz")
+	assert.Equal(s.T(), "Basic dXNlcjpwYXNz", resp.Header.Get("x-test-authorization"))
+}
+
+func TestClientCredentialsReplacesTokensBeforeExpiry(t *testing.T) {
+	tokens := newTokenServer(10)
+	defer tokens.Close()
+	source := http_credentials.NewClientCredentials(&http.Client{}, tokens.URL, "my-client", "my/secret",
+		http_credentials.WithExpiryMargin(9*time.Second+900*time.Millisecond))
+
+	first, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", first.Type)
+	cached, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, first.Value, cached.Value, "tokens must be cached")
+
+	time.Sleep(150 * time.Millisecond)
+	replaced, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Value, replaced.Value, "tokens about to expire must be replaced")
+}
+
+func TestClientCredentialsCallersStopWaitingWhenCancelled(t *testing.T) {
+	tokens := newTokenServer(3600)
+	tokens.delay = 200 * time.Millisecond
+	defer tokens.Close()
+	source := http_credentials.NewClientCredentials(&http.Client{}, tokens.URL, "my-client", "my/secret")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := source.Token(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	token, err := source.Token(context.Background())
+	require.NoError(t, err, "the token request must carry on for other callers")
+	assert.Equal(t, "token-1-", token.Value)
+}
+
+func TestClientCredentialsReportsErrors(t *testing.T) {
+	tokens := newTokenServer(3600)
+	defer tokens.Close()
+	source := http_credentials.NewClientCredentials(&http.Client{}, tokens.URL, "my-client", "wrong")
+	_, err := source.Token(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_client")
+}
@@ -0,0 +1,34 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_credentials
+
+var (
+	defaultOptions = &options{
+		serviceSources: map[string]TokenSource{},
+	}
+)
+
+type options struct {
+	serviceSources map[string]TokenSource
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.serviceSources = make(map[string]TokenSource)
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithServiceTokenSource sets the TokenSource of requests to the given service, as named by the
+// `http_ctxtags.Tripperware`.
+func WithServiceTokenSource(serviceName string, source TokenSource) Option {
+	return func(o *options) {
+		o.serviceSources[serviceName] = source
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_credentials
+
+import (
+	"context"
+	"time"
+)
+
+// Token is a credential set in the `Authorization` header of requests.
+type Token struct {
+	// Type is the authentication scheme of the token, e.g. "Bearer".
+	Type string
+	// Value is the token itself.
+	Value string
+	// Expiry is when the token expires, or zero if it doesn't.
+	Expiry time.Time
+}
+
+func (t *Token) header() string {
+	if t.Type == "" {
+		return "Bearer " + t.Value
+	}
+	return t.Type + " " + t.Value
+}
+
+// TokenSource returns tokens to authenticate requests with.
+type TokenSource interface {
+	// Token returns a token that is valid, as far as the TokenSource knows.
+	Token(ctx context.Context) (*Token, error)
+}
+
+// Refresher is implemented by TokenSources that can replace a token rejected by a server.
+type Refresher interface {
+	// Refresh returns a token to use instead of the rejected one.
+	Refresh(ctx context.Context, rejected *Token) (*Token, error)
+}
+
+// StaticToken returns a TokenSource that always returns the token with the given type (e.g. "Bearer") and value.
+func StaticToken(tokenType string, value string) TokenSource {
+	return &staticToken{&Token{Type: tokenType, Value: value}}
+}
+
+type staticToken struct {
+	token *Token
+}
+
+func (s *staticToken) Token(ctx context.Context) (*Token, error) {
+	return s.token, nil
+}
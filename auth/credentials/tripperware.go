@@ -0,0 +1,83 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_credentials
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/internal/header"
+	"github.com/improbable-eng/go-httpwares/internal/requestbody"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// Tripperware returns a new client-side ware that sets the `Authorization` header of requests to a token of the
+// TokenSource.
+//
+// TokenSources set with `WithServiceTokenSource` apply to services named by the `http_ctxtags.Tripperware`, which
+// should be placed before this one in the chain. Requests to other services use the given TokenSource, or are sent as
+// they are if it is nil.
+func Tripperware(source TokenSource, opts ...Option) httpwares.Tripperware {
+	o := evaluateOptions(opts)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			s := source
+			if svc, ok := http_ctxtags.ExtractOutbound(req).Values()[http_ctxtags.TagForCallService].(string); ok {
+				if serviceSource, ok := o.serviceSources[svc]; ok {
+					s = serviceSource
+				}
+			}
+			if s == nil || req.Header.Get("Authorization") != "" {
+				return next.RoundTrip(req)
+			}
+			token, err := s.Token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+			getBodyFn := http_requestbody.GetBody(req) // so that the body can be sent again with a new token
+			firstReq, err := withToken(req, getBodyFn, token)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := next.RoundTrip(firstReq)
+			if err != nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			refresher, ok := s.(Refresher)
+			if !ok {
+				return resp, nil
+			}
+			fresh, err := refresher.Refresh(req.Context(), token)
+			if err != nil || fresh.Value == token.Value {
+				return resp, nil // the original rejection is more useful than a failure to get a new token
+			}
+			newReq, err := withToken(req, getBodyFn, fresh)
+			if err != nil {
+				return resp, nil
+			}
+			discard(resp)
+			return next.RoundTrip(newReq)
+		})
+	}
+}
+
+// withToken returns a copy of the request with the token set and a fresh copy of the body, as RoundTrippers must not
+// modify requests.
+func withToken(req *http.Request, getBodyFn func() (io.ReadCloser, error), token *Token) (*http.Request, error) {
+	body, err := getBodyFn()
+	if err != nil {
+		return nil, err
+	}
+	newReq := http_header.CloneRequestWithHeader(req)
+	newReq.Body = body
+	newReq.Header.Set("Authorization", token.header())
+	return newReq, nil
+}
+
+func discard(resp *http.Response) {
+	io.CopyN(ioutil.Discard, resp.Body, 4096) // so that the connection can be reused
+	resp.Body.Close()
+}
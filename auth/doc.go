@@ -0,0 +1,26 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_auth` is a generic server-side HTTP authentication middleware.
+
+It doesn't do any authentication itself, instead it runs a pluggable `AuthFunc` for each request, by default the same
+for all handlers, or one per handler group of `http_ctxtags` (see `WithGroupAuthFunc`). The AuthFunc returns the
+context the handler will be called with, usually carrying the authenticated `Principal` (see `ToContext`). If it returns
+an error the request is rejected, with a 401 (Unauthorized) status code unless the error is an `*Error` saying
+otherwise.
+
+The subject and method of the Principal are set as inbound tags of `http_ctxtags` (`auth.subject` and `auth.method`),
+so that logging, tracing and monitoring pick them up.
+
+Built in AuthFuncs verify bearer tokens (`BearerToken`), HTTP Basic credentials (`Basic`) and API keys passed in a
+header (`APIKey`), with the actual checks done by a user-provided function. They can be combined with `Any`. The
+function returns the Principal of valid credentials, and requests for which it returns neither a Principal nor an error
+are rejected.
+
+Handlers (e.g. a health check) can use a different AuthFunc, or skip authentication altogether, by implementing
+`AuthFuncOverride`, for example using `Exempt`.
+
+This mirrors the auth interceptor of `go-grpc-middleware`.
+*/
+package http_auth
@@ -0,0 +1,174 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_auth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/auth"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	subjectHeader    = "x-test-subject"
+	taggedSubjHeader = "x-test-tagged-subject"
+	taggedMethHeader = "x-test-tagged-method"
+)
+
+// principalHandler reports back the authenticated principal and the tags set for it.
+func principalHandler(resp http.ResponseWriter, req *http.Request) {
+	if p, ok := http_auth.Extract(req.Context()); ok {
+		resp.Header().Set(subjectHeader, p.Subject)
+	}
+	values := http_ctxtags.ExtractInbound(req).Values()
+	if subject, ok := values[http_auth.TagForSubject].(string); ok {
+		resp.Header().Set(taggedSubjHeader, subject)
+	}
+	if method, ok := values[http_auth.TagForMethod].(string); ok {
+		resp.Header().Set(taggedMethHeader, method)
+	}
+	resp.WriteHeader(http.StatusOK)
+}
+
+func verifyToken(ctx context.Context, token string) (*http_auth.Principal, error) {
+	switch token {
+	case "good-token":
+		return &http_auth.Principal{Subject: "token-user"}, nil
+	case "forbidden-token":
+		return nil, http_auth.PermissionDenied("token not allowed here")
+	}
+	return nil, errors.New("unknown token")
+}
+
+func verifyPassword(ctx context.Context, user string, password string) (*http_auth.Principal, error) {
+	if password != "secret" {
+		return nil, errors.New("bad password")
+	}
+	return &http_auth.Principal{Subject: user}, nil
+}
+
+func verifyKey(ctx context.Context, key string) (*http_auth.Principal, error) {
+	if key != "good-key" {
+		return nil, errors.New("unknown key")
+	}
+	return &http_auth.Principal{Subject: "key-user"}, nil
+}
+
+func TestAuthSuite(t *testing.T) {
+	s := &AuthSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: http.HandlerFunc(principalHandler),
+			ServerMiddleware: []httpwares.Middleware{
+				http_ctxtags.Middleware("my_group"),
+				http_auth.Middleware(http_auth.Any(
+					http_auth.BearerToken(verifyToken),
+					http_auth.Basic("my realm", verifyPassword),
+					http_auth.APIKey("X-Api-Key", verifyKey),
+				)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type AuthSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *AuthSuite) call(modify func(req *http.Request)) *http.Response {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	modify(req)
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	return resp
+}
+
+func (s *AuthSuite) TestMissingCredentialsAreChallenged() {
+	resp := s.call(func(req *http.Request) {})
+	assert.Equal(s.T(), http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(s.T(), []string{"Bearer", `Basic realm="my realm"`}, resp.Header["Www-Authenticate"])
+	assert.Empty(s.T(), resp.Header.Get(subjectHeader), "the handler must not be called")
+}
+
+func (s *AuthSuite) TestBearerToken() {
+	resp := s.call(func(req *http.Request) { req.Header.Set("Authorization", "Bearer good-token") })
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), "token-user", resp.Header.Get(subjectHeader))
+	assert.Equal(s.T(), "token-user", resp.Header.Get(taggedSubjHeader))
+	assert.Equal(s.T(), "bearer", resp.Header.Get(taggedMethHeader))
+
+	resp = s.call(func(req *http.Request) { req.Header.Set("Authorization", "Bearer bad-token") })
+	assert.Equal(s.T(), http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(s.T(), `Bearer error="invalid_token"`, resp.Header.Get("WWW-Authenticate"))
+
+	resp = s.call(func(req *http.Request) { req.Header.Set("Authorization", "Bearer forbidden-token") })
+	assert.Equal(s.T(), http.StatusForbidden, resp.StatusCode)
+}
+
+func (s *AuthSuite) TestBasic() {
+	resp := s.call(func(req *http.Request) { req.SetBasicAuth("alice", "secret") })
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), "alice", resp.Header.Get(taggedSubjHeader))
+	assert.Equal(s.T(), "basic", resp.Header.Get(taggedMethHeader))
+
+	resp = s.call(func(req *http.Request) { req.SetBasicAuth("alice", "wrong") })
+	assert.Equal(s.T(), http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(s.T(), `Basic realm="my realm"`, resp.Header.Get("WWW-Authenticate"))
+}
+
+func (s *AuthSuite) TestAPIKey() {
+	resp := s.call(func(req *http.Request) { req.Header.Set("X-Api-Key", "good-key") })
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), "key-user", resp.Header.Get(taggedSubjHeader))
+	assert.Equal(s.T(), "api_key", resp.Header.Get(taggedMethHeader))
+
+	resp = s.call(func(req *http.Request) { req.Header.Set("X-Api-Key", "bad-key") })
+	assert.Equal(s.T(), http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestGroupAuthFuncAndExemptHandlers(t *testing.T) {
+	rejectAll := func(req *http.Request) (context.Context, error) {
+		return nil, http_auth.Unauthenticated("nobody gets in")
+	}
+	allowAll := func(req *http.Request) (context.Context, error) {
+		return http_auth.ToContext(req.Context(), &http_auth.Principal{Subject: "anyone"}), nil
+	}
+	authMiddleware := http_auth.Middleware(rejectAll, http_auth.WithGroupAuthFunc("public", allowAll))
+
+	for _, tcase := range []struct {
+		name     string
+		handler  http.Handler
+		expected int
+	}{
+		{"default", http_ctxtags.Middleware("private")(authMiddleware(http.HandlerFunc(principalHandler))), http.StatusUnauthorized},
+		{"group", http_ctxtags.Middleware("public")(authMiddleware(http.HandlerFunc(principalHandler))), http.StatusOK},
+		{"exempt", http_ctxtags.Middleware("private")(authMiddleware(http_auth.Exempt(http.HandlerFunc(principalHandler)))), http.StatusOK},
+	} {
+		req := httptest.NewRequest("GET", "http://something.local/", nil)
+		recorder := httptest.NewRecorder()
+		tcase.handler.ServeHTTP(recorder, req)
+		assert.Equal(t, tcase.expected, recorder.Code, tcase.name)
+	}
+}
+
+func TestNilContextFromAuthFuncKeepsTheRequestContext(t *testing.T) {
+	noPrincipal := func(req *http.Request) (context.Context, error) {
+		return nil, nil
+	}
+	handler := http_ctxtags.Middleware("my_group")(http_auth.Middleware(noPrincipal)(http.HandlerFunc(principalHandler)))
+	req := httptest.NewRequest("GET", "http://something.local/", nil)
+	recorder := httptest.NewRecorder()
+	require.NotPanics(t, func() { handler.ServeHTTP(recorder, req) })
+	assert.Equal(t, http.StatusOK, recorder.Code, "the request should be let through")
+	assert.Empty(t, recorder.Header().Get(subjectHeader), "no principal should be authenticated")
+}
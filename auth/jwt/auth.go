@@ -0,0 +1,43 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_jwt
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares/auth"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// AuthFunc returns a `http_auth.AuthFunc` that authenticates requests with the token of their `Authorization: Bearer`
+// header.
+//
+// The subject of the token (its `sub` claim) is the subject of the `http_auth.Principal`, whose method is "jwt" and
+// whose attributes are the claims of the token.
+func AuthFunc(keys KeySource, opts ...Option) http_auth.AuthFunc {
+	v := NewValidator(keys, opts...)
+	bearer := http_auth.BearerToken(func(ctx context.Context, token string) (*http_auth.Principal, error) {
+		claims, err := v.Validate(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+		return &http_auth.Principal{Subject: claims.Subject(), Method: "jwt", Attributes: claims}, nil
+	})
+	return func(req *http.Request) (context.Context, error) {
+		ctx, err := bearer(req)
+		if err != nil {
+			return nil, err
+		}
+		p, _ := http_auth.Extract(ctx)
+		claims := Claims(p.Attributes)
+		tags := http_ctxtags.ExtractInboundFromCtx(ctx)
+		for claim, tag := range v.opts.claimTags {
+			if value, ok := claims[claim]; ok {
+				tags.Set(tag, value)
+			}
+		}
+		return ToContext(ctx, claims), nil
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_jwt
+
+import (
+	"context"
+	"time"
+)
+
+// Claims are the claims of a validated token.
+type Claims map[string]interface{}
+
+// Subject returns the `sub` claim.
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// Issuer returns the `iss` claim.
+func (c Claims) Issuer() string {
+	s, _ := c["iss"].(string)
+	return s
+}
+
+// Audience returns the `aud` claim, which can be either a single value or a list.
+func (c Claims) Audience() []string {
+	switch aud := c["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		out := []string{}
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// ExpiresAt returns the `exp` claim, if the token has it.
+func (c Claims) ExpiresAt() (time.Time, bool) {
+	return c.time("exp")
+}
+
+// NotBefore returns the `nbf` claim, if the token has it.
+func (c Claims) NotBefore() (time.Time, bool) {
+	return c.time("nbf")
+}
+
+func (c Claims) time(name string) (time.Time, bool) {
+	seconds, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+type ctxMarker struct{}
+
+var claimsKey = &ctxMarker{}
+
+// Extract returns the claims of the token the request was authenticated with, if any.
+func Extract(ctx context.Context) (Claims, bool) {
+	c, ok := ctx.Value(claimsKey).(Claims)
+	return c, ok
+}
+
+// ToContext returns a context carrying the claims of a validated token.
+func ToContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
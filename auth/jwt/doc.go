@@ -0,0 +1,20 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_jwt` validates JSON Web Tokens (RFC 7519) passed as bearer tokens, for use with `http_auth`.
+
+Tokens signed with RS256, ES256 or HS256 are supported. Their signature is verified with a key returned by a
+`KeySource`, and the `exp`, `nbf`, `iss` and `aud` claims are checked, allowing for some clock skew between servers.
+Tokens whose `exp` or `nbf` claims aren't numbers are rejected as malformed.
+
+Keys can be fixed (`StaticKeys`, `HMACSecret`), or fetched from a JWKS (RFC 7517) endpoint of the token issuer using a
+`JWKS`. The JWKS is fetched with a user-provided `http.Client`, which can be wrapped with tripperwares
+(`httpwares.WrapClient`) to be monitored, traced or logged like other calls. It is cached, refreshed in the background
+once it gets old, and refreshed straight away when a token uses a key it doesn't know, so that keys can be rotated.
+
+`AuthFunc` returns a `http_auth.AuthFunc` authenticating requests with their token. The claims of the token are
+available to handlers with `Extract`, and selected ones can be set as inbound tags of `http_ctxtags` using
+`WithClaimTag`.
+*/
+package http_jwt
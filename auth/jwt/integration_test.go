@@ -0,0 +1,97 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_jwt_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/auth"
+	"github.com/improbable-eng/go-httpwares/auth/jwt"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// claimsHandler reports back the claims of the token and the tags set for them.
+func claimsHandler(resp http.ResponseWriter, req *http.Request) {
+	if claims, ok := http_jwt.Extract(req.Context()); ok {
+		resp.Header().Set("x-test-subject", claims.Subject())
+	}
+	values := http_ctxtags.ExtractInbound(req).Values()
+	if subject, ok := values[http_auth.TagForSubject].(string); ok {
+		resp.Header().Set("x-test-tagged-subject", subject)
+	}
+	if email, ok := values["auth.email"].(string); ok {
+		resp.Header().Set("x-test-tagged-email", email)
+	}
+	resp.WriteHeader(http.StatusOK)
+}
+
+func TestJWTSuite(t *testing.T) {
+	server := newJWKSServer()
+	server.setKeys(rsaJWK("first", rsaKey))
+	s := &JWTSuite{
+		jwksServer: server,
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: http.HandlerFunc(claimsHandler),
+			ServerMiddleware: []httpwares.Middleware{
+				http_ctxtags.Middleware("my_group"),
+				http_auth.Middleware(http_jwt.AuthFunc(
+					http_jwt.NewJWKS(&http.Client{}, server.URL),
+					http_jwt.WithAudience("my-service"),
+					http_jwt.WithClaimTag("email", "auth.email"),
+				)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type JWTSuite struct {
+	*httpwares_testing.WaresTestSuite
+	jwksServer *jwksServer
+}
+
+func (s *JWTSuite) TearDownSuite() {
+	s.jwksServer.Close()
+	s.WaresTestSuite.TearDownSuite()
+}
+
+func (s *JWTSuite) call(token string) *http.Response {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	return resp
+}
+
+func (s *JWTSuite) TestValidTokenIsAuthenticated() {
+	claims := validClaims()
+	claims["email"] = "user@example.com"
+	resp := s.call(sign("RS256", "first", rsaKey, claims))
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), "some-user", resp.Header.Get("x-test-subject"), "claims must be available to the handler")
+	assert.Equal(s.T(), "some-user", resp.Header.Get("x-test-tagged-subject"))
+	assert.Equal(s.T(), "user@example.com", resp.Header.Get("x-test-tagged-email"), "selected claims must be tagged")
+}
+
+func (s *JWTSuite) TestInvalidTokenIsRejected() {
+	claims := validClaims()
+	claims["aud"] = "other-service"
+	resp := s.call(sign("RS256", "first", rsaKey, claims))
+	assert.Equal(s.T(), http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(s.T(), `Bearer error="invalid_token"`, resp.Header.Get("WWW-Authenticate"))
+}
+
+func (s *JWTSuite) TestMissingTokenIsChallenged() {
+	resp := s.call("")
+	assert.Equal(s.T(), http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(s.T(), "Bearer", resp.Header.Get("WWW-Authenticate"))
+}
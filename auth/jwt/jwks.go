@@ -0,0 +1,248 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	defaultJWKSOptions = &jwksOptions{
+		maxAge:             time.Hour,
+		minRefreshInterval: 30 * time.Second,
+		fetchTimeout:       10 * time.Second,
+	}
+)
+
+type jwksOptions struct {
+	maxAge             time.Duration
+	minRefreshInterval time.Duration
+	fetchTimeout       time.Duration
+}
+
+// JWKSOption customizes a JWKS.
+type JWKSOption func(*jwksOptions)
+
+// WithMaxAge sets how long the keys are used before being refreshed in the background. The default is one hour.
+func WithMaxAge(maxAge time.Duration) JWKSOption {
+	return func(o *jwksOptions) {
+		o.maxAge = maxAge
+	}
+}
+
+// WithMinRefreshInterval sets the shortest time between two refreshes of the keys, which limits how often tokens
+// with unknown keys cause the keys to be fetched. The default is 30 seconds.
+func WithMinRefreshInterval(interval time.Duration) JWKSOption {
+	return func(o *jwksOptions) {
+		o.minRefreshInterval = interval
+	}
+}
+
+// WithFetchTimeout sets how long fetching the keys may take. The default is 10 seconds.
+func WithFetchTimeout(timeout time.Duration) JWKSOption {
+	return func(o *jwksOptions) {
+		o.fetchTimeout = timeout
+	}
+}
+
+// JWKS is a KeySource that fetches the keys from a JWKS (RFC 7517) endpoint.
+//
+// The keys are fetched when first needed, and refreshed in the background once older than the maximum age. Tokens
+// with a key ID that isn't known wait for the keys to be refreshed, unless they were refreshed less than the minimum
+// refresh interval ago.
+type JWKS struct {
+	client *http.Client
+	url    string
+	opts   *jwksOptions
+
+	mu          sync.Mutex
+	keys        map[string]*jsonWebKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	refreshing  chan struct{}
+	fetchErr    error
+}
+
+// NewJWKS returns a JWKS fetching the keys from the URL with the client.
+func NewJWKS(client *http.Client, url string, opts ...JWKSOption) *JWKS {
+	o := &jwksOptions{}
+	*o = *defaultJWKSOptions
+	for _, opt := range opts {
+		opt(o)
+	}
+	return &JWKS{client: client, url: url, opts: o}
+}
+
+func (j *JWKS) Key(ctx context.Context, keyID string, algorithm string) (interface{}, error) {
+	j.mu.Lock()
+	if key, ok := j.keys[keyID]; ok {
+		if time.Since(j.fetchedAt) > j.opts.maxAge {
+			j.refreshLocked() // keep using the current keys in the meantime
+		}
+		j.mu.Unlock()
+		return key.forAlgorithm(algorithm)
+	}
+	if j.refreshing == nil && !j.attemptedAt.IsZero() && time.Since(j.attemptedAt) < j.opts.minRefreshInterval {
+		err := j.fetchErr
+		j.mu.Unlock()
+		return nil, unknownKeyError(keyID, err)
+	}
+	done := j.refreshLocked()
+	j.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	j.mu.Lock()
+	key, ok := j.keys[keyID]
+	err := j.fetchErr
+	j.mu.Unlock()
+	if !ok {
+		return nil, unknownKeyError(keyID, err)
+	}
+	return key.forAlgorithm(algorithm)
+}
+
+// refreshLocked starts refreshing the keys, unless that is in progress already, and returns a channel closed once done.
+func (j *JWKS) refreshLocked() <-chan struct{} {
+	if j.refreshing != nil {
+		return j.refreshing
+	}
+	done := make(chan struct{})
+	j.refreshing = done
+	j.attemptedAt = time.Now()
+	go func() {
+		keys, err := j.fetch()
+		j.mu.Lock()
+		if err == nil {
+			j.keys = keys
+			j.fetchedAt = time.Now()
+		}
+		j.fetchErr = err
+		j.refreshing = nil
+		j.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+func (j *JWKS) fetch() (map[string]*jsonWebKey, error) {
+	// Not tied to the request that happened to need the keys, as others may be waiting for them too.
+	ctx, cancel := context.WithTimeout(context.Background(), j.opts.fetchTimeout)
+	defer cancel()
+	req, err := http.NewRequest("GET", j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := j.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwt: fetching keys failed with status %v", resp.StatusCode)
+	}
+	set := &struct {
+		Keys []*jsonWebKey `json:"keys"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(set); err != nil {
+		return nil, fmt.Errorf("jwt: malformed keys: %v", err)
+	}
+	keys := make(map[string]*jsonWebKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		if err := k.parse(); err != nil {
+			continue // keys of types we don't support are of no use anyway
+		}
+		keys[k.KeyID] = k
+	}
+	return keys, nil
+}
+
+func unknownKeyError(keyID string, fetchErr error) error {
+	if fetchErr != nil {
+		return fmt.Errorf("jwt: unknown key %q, fetching keys failed: %v", keyID, fetchErr)
+	}
+	return fmt.Errorf("jwt: unknown key %q", keyID)
+}
+
+// jsonWebKey is a public key of a JWKS.
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+
+	key interface{}
+}
+
+func (k *jsonWebKey) parse() error {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return err
+		}
+		k.key = &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		if k.Curve != "P-256" {
+			return fmt.Errorf("jwt: unsupported curve %q", k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return fmt.Errorf("jwt: point not on curve")
+		}
+		k.key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	default:
+		return fmt.Errorf("jwt: unsupported key type %q", k.KeyType)
+	}
+	return nil
+}
+
+// forAlgorithm returns the key, if it may be used with the algorithm.
+func (k *jsonWebKey) forAlgorithm(algorithm string) (interface{}, error) {
+	if k.Algorithm != "" && k.Algorithm != algorithm {
+		return nil, fmt.Errorf("jwt: key %q is for %v, not %v", k.KeyID, k.Algorithm, algorithm)
+	}
+	return k.key, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("jwt: malformed key")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
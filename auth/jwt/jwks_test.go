@@ -0,0 +1,80 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_jwt_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/auth/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWKSRefreshesOnUnknownKey(t *testing.T) {
+	server := newJWKSServer()
+	defer server.Close()
+	server.setKeys(rsaJWK("first", rsaKey), ecJWK("ec", ecKey))
+
+	var calls int32
+	client := httpwares.WrapClient(&http.Client{}, func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return next.RoundTrip(req)
+		})
+	})
+	v := http_jwt.NewValidator(http_jwt.NewJWKS(client, server.URL, http_jwt.WithMinRefreshInterval(50*time.Millisecond)))
+
+	_, err := v.Validate(context.Background(), sign("RS256", "first", rsaKey, validClaims()))
+	require.NoError(t, err, "tokens must be verified with the fetched keys")
+	_, err = v.Validate(context.Background(), sign("ES256", "ec", ecKey, validClaims()))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&server.fetches), "keys must be cached")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls), "keys must be fetched with the given client")
+
+	time.Sleep(60 * time.Millisecond) // the minimum refresh interval
+	rotated, _ := rsa.GenerateKey(rand.Reader, 2048)
+	server.setKeys(rsaJWK("first", rsaKey), rsaJWK("second", rotated))
+	_, err = v.Validate(context.Background(), sign("RS256", "second", rotated, validClaims()))
+	require.NoError(t, err, "tokens with unknown keys must cause the keys to be refreshed")
+	assert.EqualValues(t, 2, atomic.LoadInt32(&server.fetches))
+
+	_, err = v.Validate(context.Background(), sign("RS256", "third", rotated, validClaims()))
+	require.Error(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&server.fetches), "keys must not be refreshed more often than the minimum interval")
+}
+
+func TestJWKSRefreshesOldKeysInBackground(t *testing.T) {
+	server := newJWKSServer()
+	defer server.Close()
+	server.setKeys(rsaJWK("first", rsaKey))
+	v := http_jwt.NewValidator(http_jwt.NewJWKS(&http.Client{}, server.URL, http_jwt.WithMaxAge(10*time.Millisecond)))
+
+	_, err := v.Validate(context.Background(), sign("RS256", "first", rsaKey, validClaims()))
+	require.NoError(t, err)
+	time.Sleep(20 * time.Millisecond)
+	_, err = v.Validate(context.Background(), sign("RS256", "first", rsaKey, validClaims()))
+	require.NoError(t, err, "old keys must still be used while being refreshed")
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&server.fetches) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.EqualValues(t, 2, atomic.LoadInt32(&server.fetches), "old keys must be refreshed")
+}
+
+func TestJWKSKeysAreOnlyUsedForTheirAlgorithm(t *testing.T) {
+	server := newJWKSServer()
+	defer server.Close()
+	server.setKeys(rsaJWK("first", rsaKey))
+	v := http_jwt.NewValidator(http_jwt.NewJWKS(&http.Client{}, server.URL))
+
+	_, err := v.Validate(context.Background(), sign("HS256", "first", rsaKey.PublicKey.N.Bytes(), validClaims()))
+	assert.Error(t, err, "keys with an algorithm must not be used for others")
+}
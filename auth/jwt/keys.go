@@ -0,0 +1,35 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_jwt
+
+import (
+	"context"
+	"fmt"
+)
+
+// StaticKeys is a KeySource with a fixed set of keys, by key ID.
+//
+// Tokens without a key ID use the key with an empty ID.
+type StaticKeys map[string]interface{}
+
+func (k StaticKeys) Key(ctx context.Context, keyID string, algorithm string) (interface{}, error) {
+	key, ok := k[keyID]
+	if !ok {
+		return nil, fmt.Errorf("jwt: unknown key %q", keyID)
+	}
+	return key, nil
+}
+
+// HMACSecret returns a KeySource with a single secret for HS256 tokens.
+func HMACSecret(secret []byte) KeySource {
+	return &hmacSecret{secret}
+}
+
+type hmacSecret struct {
+	secret []byte
+}
+
+func (k *hmacSecret) Key(ctx context.Context, keyID string, algorithm string) (interface{}, error) {
+	return k.secret, nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_jwt
+
+import "time"
+
+var (
+	defaultOptions = &options{
+		algorithms: []string{RS256, ES256, HS256},
+		issuers:    nil,
+		audience:   "",
+		clockSkew:  time.Minute,
+		claimTags:  map[string]string{},
+		clock:      time.Now,
+	}
+)
+
+type options struct {
+	algorithms []string
+	issuers    []string
+	audience   string
+	clockSkew  time.Duration
+	claimTags  map[string]string
+	clock      func() time.Time
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.claimTags = make(map[string]string)
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithAlgorithms sets the signing algorithms that tokens can use. By default all the supported ones are allowed.
+//
+// Whatever the algorithms allowed, tokens are only verified with keys of the type their algorithm uses.
+func WithAlgorithms(algorithms ...string) Option {
+	return func(o *options) {
+		o.algorithms = algorithms
+	}
+}
+
+// WithIssuers sets the issuers that tokens must come from, as named by their `iss` claim.
+//
+// By default tokens from any issuer are accepted.
+func WithIssuers(issuers ...string) Option {
+	return func(o *options) {
+		o.issuers = issuers
+	}
+}
+
+// WithAudience sets the audience that tokens must be meant for, as named by their `aud` claim.
+//
+// By default tokens for any audience are accepted.
+func WithAudience(audience string) Option {
+	return func(o *options) {
+		o.audience = audience
+	}
+}
+
+// WithClockSkew sets how much the clocks of the issuer and of this server may differ when checking the `exp` and `nbf`
+// claims. The default is one minute.
+func WithClockSkew(skew time.Duration) Option {
+	return func(o *options) {
+		o.clockSkew = skew
+	}
+}
+
+// WithClaimTag sets the value of the claim, if the token has it, as the given inbound tag of `http_ctxtags`.
+func WithClaimTag(claim string, tag string) Option {
+	return func(o *options) {
+		o.claimTags[claim] = tag
+	}
+}
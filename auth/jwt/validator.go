@@ -0,0 +1,188 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Signing algorithms of tokens that are supported.
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	HS256 = "HS256"
+)
+
+var (
+	ErrMalformedToken   = errors.New("jwt: malformed token")
+	ErrAlgorithm        = errors.New("jwt: signing algorithm not allowed")
+	ErrInvalidSignature = errors.New("jwt: invalid signature")
+	ErrExpired          = errors.New("jwt: token expired")
+	ErrNotValidYet      = errors.New("jwt: token not valid yet")
+	ErrIssuer           = errors.New("jwt: token from unexpected issuer")
+	ErrAudience         = errors.New("jwt: token for unexpected audience")
+)
+
+// KeySource returns the key that verifies the signature of tokens with the given key ID (`kid`) and algorithm.
+//
+// Keys are `*rsa.PublicKey` for RS256, `*ecdsa.PublicKey` for ES256 and `[]byte` for HS256.
+type KeySource interface {
+	Key(ctx context.Context, keyID string, algorithm string) (interface{}, error)
+}
+
+// Validator validates tokens.
+type Validator struct {
+	keys KeySource
+	opts *options
+}
+
+// NewValidator returns a Validator of tokens signed with keys from the KeySource.
+func NewValidator(keys KeySource, opts ...Option) *Validator {
+	return &Validator{keys: keys, opts: evaluateOptions(opts)}
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// Validate checks the signature and claims of the token, returning its claims if it is valid.
+func (v *Validator) Validate(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	h := &header{}
+	if err := decodeSegment(parts[0], h); err != nil {
+		return nil, err
+	}
+	if !v.algorithmAllowed(h.Algorithm) {
+		return nil, ErrAlgorithm
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	key, err := v.keys.Key(ctx, h.KeyID, h.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(h.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+	claims := Claims{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Validator) algorithmAllowed(algorithm string) bool {
+	for _, a := range v.opts.algorithms {
+		if a == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Validator) checkClaims(claims Claims) error {
+	for _, name := range []string{"exp", "nbf"} {
+		if value, ok := claims[name]; ok {
+			if _, isNumber := value.(float64); !isNumber {
+				return ErrMalformedToken // a date that can't be checked mustn't be ignored
+			}
+		}
+	}
+	now := v.opts.clock()
+	if exp, ok := claims.ExpiresAt(); ok && !now.Before(exp.Add(v.opts.clockSkew)) {
+		return ErrExpired
+	}
+	if nbf, ok := claims.NotBefore(); ok && now.Before(nbf.Add(-v.opts.clockSkew)) {
+		return ErrNotValidYet
+	}
+	if len(v.opts.issuers) > 0 && !contains(v.opts.issuers, claims.Issuer()) {
+		return ErrIssuer
+	}
+	if v.opts.audience != "" && !contains(claims.Audience(), v.opts.audience) {
+		return ErrAudience
+	}
+	return nil
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+// verifySignature checks the signature with the key, which must be of the type the algorithm uses.
+func verifySignature(algorithm string, key interface{}, signed []byte, signature []byte) error {
+	digest := sha256.Sum256(signed)
+	switch algorithm {
+	case RS256:
+		k, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("jwt: key of type %T can't verify %v", key, algorithm)
+		}
+		if rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], signature) != nil {
+			return ErrInvalidSignature
+		}
+	case ES256:
+		k, ok := key.(*ecdsa.PublicKey)
+		if !ok || k.Curve != elliptic.P256() {
+			return fmt.Errorf("jwt: key of type %T can't verify %v", key, algorithm)
+		}
+		if len(signature) != 64 {
+			return ErrInvalidSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(k, digest[:], r, s) {
+			return ErrInvalidSignature
+		}
+	case HS256:
+		k, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("jwt: key of type %T can't verify %v", key, algorithm)
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrAlgorithm
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
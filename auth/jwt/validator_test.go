@@ -0,0 +1,81 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_jwt_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares/auth/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatorAcceptsSupportedAlgorithms(t *testing.T) {
+	keys := http_jwt.StaticKeys{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey, "hmac": secret}
+	v := http_jwt.NewValidator(keys)
+	for _, token := range []string{
+		sign("RS256", "rsa", rsaKey, validClaims()),
+		sign("ES256", "ec", ecKey, validClaims()),
+		sign("HS256", "hmac", secret, validClaims()),
+	} {
+		claims, err := v.Validate(context.Background(), token)
+		require.NoError(t, err, "token %v must be valid", token)
+		assert.Equal(t, "some-user", claims.Subject())
+		assert.Equal(t, []string{"my-service"}, claims.Audience())
+	}
+}
+
+func TestValidatorRejectsInvalidTokens(t *testing.T) {
+	keys := http_jwt.StaticKeys{"rsa": &rsaKey.PublicKey, "hmac": secret}
+	v := http_jwt.NewValidator(keys,
+		http_jwt.WithIssuers("https://issuer.local"),
+		http_jwt.WithAudience("my-service"),
+		http_jwt.WithClockSkew(10*time.Second),
+	)
+	withClaim := func(name string, value interface{}) map[string]interface{} {
+		c := validClaims()
+		c[name] = value
+		return c
+	}
+	valid := sign("RS256", "rsa", rsaKey, validClaims())
+	for _, tcase := range []struct {
+		name     string
+		token    string
+		expected error
+	}{
+		{"malformed", "not.a-token", http_jwt.ErrMalformedToken},
+		{"tampered", valid[:strings.LastIndex(valid, ".")] + ".AAAA", http_jwt.ErrInvalidSignature},
+		{"none algorithm", encodeSegment(map[string]string{"alg": "none"}) + "." + encodeSegment(validClaims()) + ".", http_jwt.ErrAlgorithm},
+		{"expired", sign("RS256", "rsa", rsaKey, withClaim("exp", time.Now().Add(-time.Minute).Unix())), http_jwt.ErrExpired},
+		{"not valid yet", sign("RS256", "rsa", rsaKey, withClaim("nbf", time.Now().Add(time.Minute).Unix())), http_jwt.ErrNotValidYet},
+		{"expiry as a string", sign("RS256", "rsa", rsaKey, withClaim("exp", time.Now().Add(-time.Minute).Format(time.RFC3339))), http_jwt.ErrMalformedToken},
+		{"not before as null", sign("RS256", "rsa", rsaKey, withClaim("nbf", nil)), http_jwt.ErrMalformedToken},
+		{"wrong issuer", sign("RS256", "rsa", rsaKey, withClaim("iss", "https://other.local")), http_jwt.ErrIssuer},
+		{"wrong audience", sign("RS256", "rsa", rsaKey, withClaim("aud", []string{"other", "services"})), http_jwt.ErrAudience},
+	} {
+		_, err := v.Validate(context.Background(), tcase.token)
+		assert.Equal(t, tcase.expected, err, tcase.name)
+	}
+
+	_, err := v.Validate(context.Background(), sign("HS256", "rsa", []byte("public key as secret"), validClaims()))
+	assert.Error(t, err, "keys must only be used for the algorithm of their type")
+}
+
+func TestValidatorAllowsClockSkew(t *testing.T) {
+	v := http_jwt.NewValidator(http_jwt.HMACSecret(secret), http_jwt.WithClockSkew(time.Minute))
+	c := validClaims()
+	c["exp"] = time.Now().Add(-30 * time.Second).Unix()
+	c["nbf"] = time.Now().Add(30 * time.Second).Unix()
+	_, err := v.Validate(context.Background(), sign("HS256", "", secret, c))
+	assert.NoError(t, err, "claims within the clock skew must be accepted")
+}
+
+func TestValidatorRestrictsAlgorithms(t *testing.T) {
+	v := http_jwt.NewValidator(http_jwt.HMACSecret(secret), http_jwt.WithAlgorithms(http_jwt.RS256))
+	_, err := v.Validate(context.Background(), sign("HS256", "", secret, validClaims()))
+	assert.Equal(t, http_jwt.ErrAlgorithm, err)
+}
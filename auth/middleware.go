@@ -0,0 +1,65 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_auth
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/logging/logrus/ctxlogrus"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/sirupsen/logrus"
+)
+
+// Middleware returns a http.Handler middleware that authenticates requests with the given AuthFunc.
+//
+// Group AuthFuncs need `http_ctxtags.Middleware` to be placed before this one in the chain. Requests that fail
+// authentication are handled by the ErrorHandlerFunc, and their error is added to the request's log statement.
+func Middleware(authFunc AuthFunc, opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			tags := http_ctxtags.ExtractInbound(req)
+			f := authFunc
+			if group, ok := tags.Values()[http_ctxtags.TagForHandlerGroup].(string); ok {
+				if groupFunc, ok := o.groupAuthFuncs[group]; ok {
+					f = groupFunc
+				}
+			}
+			if override, ok := next.(AuthFuncOverride); ok {
+				f = override.AuthFuncOverride
+			}
+			ctx, err := f(req)
+			if err != nil {
+				ctxlogrus.AddFields(req.Context(), logrus.Fields{"auth.error": err.Error()})
+				o.errorHandler(resp, req, err)
+				return
+			}
+			if ctx == nil {
+				ctx = req.Context()
+			}
+			if p, ok := Extract(ctx); ok {
+				tags.Set(TagForSubject, p.Subject)
+				if p.Method != "" {
+					tags.Set(TagForMethod, p.Method)
+				}
+			}
+			next.ServeHTTP(resp, req.WithContext(ctx))
+		})
+	}
+}
+
+// Exempt returns a handler that skips authentication, e.g. for health checks.
+func Exempt(handler http.Handler) http.Handler {
+	return &exemptHandler{handler}
+}
+
+type exemptHandler struct {
+	http.Handler
+}
+
+func (h *exemptHandler) AuthFuncOverride(req *http.Request) (context.Context, error) {
+	return req.Context(), nil
+}
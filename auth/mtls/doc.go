@@ -0,0 +1,19 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_mtls` authorizes requests by the identity of their mutual TLS peer.
+
+The Middleware lets through requests whose client certificate was verified by the server and has an identity (a URI,
+such as a SPIFFE ID, or a DNS name of its subject alternative names) matching one of the allowed patterns. Patterns
+can be set for all handlers (`WithAllowedPeers`) or per handler group of `http_ctxtags` (`WithGroupAllowedPeers`), and
+are matched with `path.Match`, so that e.g. "spiffe://example.org/ns/prod/sa/*" allows all service accounts of the
+prod namespace.
+
+Requests without a verified client certificate are rejected with a 401 (Unauthorized) status code, and ones with a
+certificate that isn't allowed with a 403 (Forbidden) status code.
+
+The server needs to ask for client certificates for this to work, e.g. with `tls.VerifyClientCertIfGiven`. The
+identity of peers can also be tagged with `http_ctxtags.WithTLSPeerTags`.
+*/
+package http_mtls
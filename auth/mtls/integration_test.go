@@ -0,0 +1,108 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_mtls_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"testing"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/auth/mtls"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMTLSSuite(t *testing.T) {
+	ca, err := httpwares_testing.NewCertificateAuthority("test-ca")
+	require.NoError(t, err, "creating a CA must not fail")
+	frontend, err := ca.IssueClientCertificate("frontend", nil, []string{"spiffe://example.org/ns/prod/sa/frontend"})
+	require.NoError(t, err, "issuing a client certificate must not fail")
+	batch, err := ca.IssueClientCertificate("batch", []string{"batch.example.org"}, nil)
+	require.NoError(t, err, "issuing a client certificate must not fail")
+	untrustedCA, err := httpwares_testing.NewCertificateAuthority("untrusted-ca")
+	require.NoError(t, err, "creating a CA must not fail")
+	untrusted, err := untrustedCA.IssueClientCertificate("frontend", nil, []string{"spiffe://example.org/ns/prod/sa/frontend"})
+	require.NoError(t, err, "issuing a client certificate must not fail")
+
+	mux := http.NewServeMux()
+	mux.Handle("/", http.HandlerFunc(httpwares_testing.PingBackHandler(http.StatusOK)))
+	s := &MTLSSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: mux,
+			ServerMiddleware: []httpwares.Middleware{
+				http_ctxtags.Middleware("default"),
+				// Middleware wrapping a mux doesn't know its handlers' groups, so they're set by path here.
+				func(next http.Handler) http.Handler {
+					return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+						if req.URL.Path == "/admin" {
+							http_ctxtags.ExtractInbound(req).Set(http_ctxtags.TagForHandlerGroup, "admin")
+						}
+						next.ServeHTTP(resp, req)
+					})
+				},
+				http_mtls.Middleware(
+					http_mtls.WithAllowedPeers("spiffe://example.org/ns/prod/sa/*", "*.example.org"),
+					http_mtls.WithGroupAllowedPeers("admin", "batch.example.org"),
+				),
+			},
+			ServerClientCAs: ca.CertPool(),
+		},
+		frontend:  frontend,
+		batch:     batch,
+		untrusted: untrusted,
+	}
+	suite.Run(t, s)
+}
+
+type MTLSSuite struct {
+	*httpwares_testing.WaresTestSuite
+	frontend  tls.Certificate
+	batch     tls.Certificate
+	untrusted tls.Certificate
+}
+
+func (s *MTLSSuite) call(path string, certs ...tls.Certificate) int {
+	s.ClientCertificates = certs
+	client := s.NewClient()
+	s.ClientCertificates = nil
+	req, _ := http.NewRequest("GET", "https://something.local"+path, nil)
+	resp, err := client.Do(req)
+	require.NoError(s.T(), err, "call shouldn't fail")
+	resp.Body.Close()
+	if resp.TLS == nil {
+		s.T().Skip("mutual TLS needs the server to use TLS")
+	}
+	return resp.StatusCode
+}
+
+func (s *MTLSSuite) TestAllowedPeersAreLetThrough() {
+	assert.Equal(s.T(), http.StatusOK, s.call("/someurl", s.frontend), "SPIFFE ID matching a pattern must be allowed")
+	assert.Equal(s.T(), http.StatusOK, s.call("/someurl", s.batch), "DNS name matching a pattern must be allowed")
+}
+
+func (s *MTLSSuite) TestRequestWithoutCertificateIsUnauthorized() {
+	assert.Equal(s.T(), http.StatusUnauthorized, s.call("/someurl"))
+}
+
+func (s *MTLSSuite) TestUnverifiedCertificateIsUnauthorized() {
+	// With `tls.VerifyClientCertIfGiven` the handshake fails for certificates that don't verify.
+	s.ClientCertificates = []tls.Certificate{s.untrusted}
+	client := s.NewClient()
+	s.ClientCertificates = nil
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		assert.Equal(s.T(), http.StatusUnauthorized, resp.StatusCode, "unverified certificate must not be let through")
+	}
+}
+
+func (s *MTLSSuite) TestGroupPatternsReplaceDefaultOnes() {
+	assert.Equal(s.T(), http.StatusForbidden, s.call("/admin", s.frontend), "peers not allowed for the group must be forbidden")
+	assert.Equal(s.T(), http.StatusOK, s.call("/admin", s.batch), "peers allowed for the group must be let through")
+}
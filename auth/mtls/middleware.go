@@ -0,0 +1,55 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_mtls
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/internal/peercert"
+	"github.com/improbable-eng/go-httpwares/logging/logrus/ctxlogrus"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/sirupsen/logrus"
+)
+
+// Middleware returns a http.Handler middleware that only lets through requests from allowed mutual TLS peers.
+//
+// Group patterns need `http_ctxtags.Middleware` to be placed before this one in the chain.
+func Middleware(opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			cert, ok := http_peercert.VerifiedCertificate(req.TLS)
+			if !ok {
+				ctxlogrus.AddFields(req.Context(), logrus.Fields{"auth.error": "no verified client certificate"})
+				http.Error(resp, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			patterns := o.allowedPeers
+			if group, ok := http_ctxtags.ExtractInbound(req).Values()[http_ctxtags.TagForHandlerGroup].(string); ok {
+				if groupPatterns, ok := o.groupAllowedPeers[group]; ok {
+					patterns = groupPatterns
+				}
+			}
+			if !anyMatches(patterns, http_peercert.Identities(cert)) {
+				ctxlogrus.AddFields(req.Context(), logrus.Fields{"auth.error": "client certificate not allowed"})
+				http.Error(resp, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(resp, req)
+		})
+	}
+}
+
+func anyMatches(patterns []string, identities []string) bool {
+	for _, pattern := range patterns {
+		for _, id := range identities {
+			if matched, err := path.Match(pattern, id); err == nil && matched {
+				return true
+			}
+		}
+	}
+	return false
+}
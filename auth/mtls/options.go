@@ -0,0 +1,46 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_mtls
+
+var (
+	defaultOptions = &options{
+		allowedPeers:      nil,
+		groupAllowedPeers: map[string][]string{},
+	}
+)
+
+type options struct {
+	allowedPeers      []string
+	groupAllowedPeers map[string][]string
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.groupAllowedPeers = make(map[string][]string)
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithAllowedPeers sets the patterns of the identities of peers allowed to call handlers without patterns of their
+// own group.
+//
+// By default no peers are allowed.
+func WithAllowedPeers(patterns ...string) Option {
+	return func(o *options) {
+		o.allowedPeers = patterns
+	}
+}
+
+// WithGroupAllowedPeers sets the patterns of the identities of peers allowed to call handlers of the given group, as
+// set by `http_ctxtags.Middleware`, instead of the ones of `WithAllowedPeers`.
+func WithGroupAllowedPeers(handlerGroup string, patterns ...string) Option {
+	return func(o *options) {
+		o.groupAllowedPeers[handlerGroup] = patterns
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_auth
+
+import "net/http"
+
+var (
+	defaultOptions = &options{
+		groupAuthFuncs: map[string]AuthFunc{},
+		errorHandler:   DefaultErrorHandler,
+	}
+)
+
+type options struct {
+	groupAuthFuncs map[string]AuthFunc
+	errorHandler   ErrorHandlerFunc
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.groupAuthFuncs = make(map[string]AuthFunc)
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithGroupAuthFunc sets the AuthFunc of requests to handlers of the given group, as set by `http_ctxtags.Middleware`.
+func WithGroupAuthFunc(handlerGroup string, f AuthFunc) Option {
+	return func(o *options) {
+		o.groupAuthFuncs[handlerGroup] = f
+	}
+}
+
+// ErrorHandlerFunc handles the response of a request that failed authentication with the error `err`.
+type ErrorHandlerFunc func(resp http.ResponseWriter, req *http.Request, err error)
+
+// WithErrorHandler customizes how responses of requests that failed authentication are handled.
+func WithErrorHandler(f ErrorHandlerFunc) Option {
+	return func(o *options) {
+		o.errorHandler = f
+	}
+}
+
+// DefaultErrorHandler responds with the status code and `WWW-Authenticate` challenge of the error if it is an `*Error`,
+// and with a 401 (Unauthorized) status code otherwise.
+//
+// The message of the error isn't sent to the client.
+func DefaultErrorHandler(resp http.ResponseWriter, req *http.Request, err error) {
+	code := http.StatusUnauthorized
+	if authErr, ok := err.(*Error); ok {
+		code = authErr.Code
+		for _, challenge := range authErr.Challenges {
+			resp.Header().Add("WWW-Authenticate", challenge)
+		}
+	}
+	http.Error(resp, http.StatusText(code), code)
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errNoPrincipal = errors.New("no principal for the credentials")
+
+// BearerToken returns an AuthFunc that authenticates requests with the token of their `Authorization: Bearer` header
+// (RFC 6750), using the `verify` function.
+func BearerToken(verify func(ctx context.Context, token string) (*Principal, error)) AuthFunc {
+	return func(req *http.Request) (context.Context, error) {
+		token, ok := bearerToken(req)
+		if !ok {
+			return nil, missingCredentials("Bearer")
+		}
+		p, err := verify(req.Context(), token)
+		return authenticated(req.Context(), p, err, "bearer", `Bearer error="invalid_token"`)
+	}
+}
+
+// Basic returns an AuthFunc that authenticates requests with their HTTP Basic credentials (RFC 7617), using the
+// `verify` function.
+func Basic(realm string, verify func(ctx context.Context, user string, password string) (*Principal, error)) AuthFunc {
+	challenge := "Basic realm=" + strconv.Quote(realm)
+	return func(req *http.Request) (context.Context, error) {
+		user, password, ok := req.BasicAuth()
+		if !ok {
+			return nil, missingCredentials(challenge)
+		}
+		p, err := verify(req.Context(), user, password)
+		return authenticated(req.Context(), p, err, "basic", challenge)
+	}
+}
+
+// APIKey returns an AuthFunc that authenticates requests with the API key in the given header, using the `verify`
+// function.
+func APIKey(headerName string, verify func(ctx context.Context, key string) (*Principal, error)) AuthFunc {
+	return func(req *http.Request) (context.Context, error) {
+		key := req.Header.Get(headerName)
+		if key == "" {
+			return nil, &Error{Code: http.StatusUnauthorized, Message: "missing credentials", missingCredentials: true}
+		}
+		p, err := verify(req.Context(), key)
+		return authenticated(req.Context(), p, err, "api_key", "")
+	}
+}
+
+// Any returns an AuthFunc that authenticates requests with the first of the AuthFuncs whose credentials they have.
+//
+// Requests with credentials that are rejected aren't tried with the other AuthFuncs. Requests without any credentials
+// are rejected with the challenges of all the AuthFuncs.
+func Any(funcs ...AuthFunc) AuthFunc {
+	return func(req *http.Request) (context.Context, error) {
+		challenges := []string{}
+		for _, f := range funcs {
+			ctx, err := f(req)
+			if !IsMissingCredentials(err) {
+				return ctx, err
+			}
+			challenges = append(challenges, err.(*Error).Challenges...)
+		}
+		return nil, &Error{
+			Code:               http.StatusUnauthorized,
+			Challenges:         challenges,
+			Message:            "missing credentials",
+			missingCredentials: true,
+		}
+	}
+}
+
+func bearerToken(req *http.Request) (string, bool) {
+	const prefix = "bearer "
+	header := req.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	token := strings.TrimSpace(header[len(prefix):])
+	return token, token != ""
+}
+
+// withChallenge turns errors of verify functions into an `*Error`, unless they are one already.
+func withChallenge(err error, challenge string) error {
+	if _, ok := err.(*Error); ok {
+		return err
+	}
+	authErr := Unauthenticated(err.Error())
+	if challenge != "" {
+		authErr.Challenges = []string{challenge}
+	}
+	return authErr
+}
+
+// authenticated turns the result of a verify function into the result of an AuthFunc, setting the Principal in the
+// context without modifying it. Verify functions returning neither a Principal nor an error reject the request.
+func authenticated(ctx context.Context, p *Principal, err error, method string, challenge string) (context.Context, error) {
+	if err == nil && p == nil {
+		err = errNoPrincipal
+	}
+	if err != nil {
+		return nil, withChallenge(err, challenge)
+	}
+	if p.Method == "" {
+		withMethod := *p
+		withMethod.Method = method
+		p = &withMethod
+	}
+	return ToContext(ctx, p), nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBearerToken(t *testing.T) {
+	for header, expected := range map[string]string{
+		"":                "",
+		"Bearer":          "",
+		"Bearer   ":       "",
+		"Basic abc":       "",
+		"Bearer abc":      "abc",
+		"bearer abc":      "abc",
+		"BEARER  abc.def": "abc.def",
+	} {
+		req, _ := http.NewRequest("GET", "http://something.local/", nil)
+		req.Header.Set("Authorization", header)
+		token, ok := bearerToken(req)
+		assert.Equal(t, expected != "", ok, "header %q", header)
+		assert.Equal(t, expected, token, "header %q", header)
+	}
+}
+
+func TestAnyUsesFirstCredentialsPresent(t *testing.T) {
+	rejected := errors.New("bad token")
+	f := Any(
+		BearerToken(func(ctx context.Context, token string) (*Principal, error) {
+			return nil, rejected
+		}),
+		Basic("test", func(ctx context.Context, user string, password string) (*Principal, error) {
+			return &Principal{Subject: user}, nil
+		}),
+	)
+
+	req, _ := http.NewRequest("GET", "http://something.local/", nil)
+	_, err := f(req)
+	require.True(t, IsMissingCredentials(err), "requests without credentials must be missing them")
+	assert.Equal(t, []string{"Bearer", `Basic realm="test"`}, err.(*Error).Challenges)
+
+	req.SetBasicAuth("user", "pass")
+	ctx, err := f(req)
+	require.NoError(t, err)
+	p, ok := Extract(ctx)
+	require.True(t, ok)
+	assert.Equal(t, "user", p.Subject)
+	assert.Equal(t, "basic", p.Method)
+
+	req.Header.Set("Authorization", "Bearer abc")
+	_, err = f(req)
+	require.Error(t, err, "rejected credentials must not be tried with other funcs")
+	assert.False(t, IsMissingCredentials(err))
+	assert.Equal(t, []string{`Bearer error="invalid_token"`}, err.(*Error).Challenges)
+}
+
+func TestVerifierWithoutPrincipalRejects(t *testing.T) {
+	f := BearerToken(func(ctx context.Context, token string) (*Principal, error) {
+		return nil, nil
+	})
+	req, _ := http.NewRequest("GET", "http://something.local/", nil)
+	req.Header.Set("Authorization", "Bearer abc")
+	ctx, err := f(req)
+	require.Error(t, err, "credentials without a principal must be rejected")
+	assert.Nil(t, ctx)
+	assert.Equal(t, http.StatusUnauthorized, err.(*Error).Code)
+	assert.Equal(t, []string{`Bearer error="invalid_token"`}, err.(*Error).Challenges)
+}
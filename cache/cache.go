@@ -0,0 +1,95 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache stores the cached responses. It must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored for the key, and whether there was one.
+	Get(key string) ([]byte, bool)
+	// Set stores the value for the key. Implementations are free to drop values, e.g. when they run out of space.
+	Set(key string, value []byte)
+	// Delete removes the value stored for the key, if there is one.
+	Delete(key string)
+}
+
+// MemoryCache is an in-memory Cache that evicts the least recently used values once it reaches its maximum size.
+type MemoryCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	bytes    int64
+	order    *list.List // of *memoryCacheItem, most recently used at the front
+	items    map[string]*list.Element
+}
+
+type memoryCacheItem struct {
+	key   string
+	value []byte
+}
+
+// NewMemoryCache returns a MemoryCache that holds up to maxBytes of keys and values.
+func NewMemoryCache(maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*memoryCacheItem).value, true
+}
+
+func (c *MemoryCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleteLocked(key)
+	size := itemSize(key, value)
+	if size > c.maxBytes {
+		return // would evict everything else and still not fit
+	}
+	c.items[key] = c.order.PushFront(&memoryCacheItem{key: key, value: value})
+	c.bytes += size
+	for c.bytes > c.maxBytes {
+		c.deleteLocked(c.order.Back().Value.(*memoryCacheItem).key)
+	}
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleteLocked(key)
+}
+
+// Size returns the number of bytes of keys and values held.
+func (c *MemoryCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.bytes
+}
+
+func (c *MemoryCache) deleteLocked(key string) {
+	elem, ok := c.items[key]
+	if !ok {
+		return
+	}
+	item := c.order.Remove(elem).(*memoryCacheItem)
+	delete(c.items, key)
+	c.bytes -= itemSize(item.key, item.value)
+}
+
+func itemSize(key string, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
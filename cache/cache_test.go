@@ -0,0 +1,52 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_cache
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewMemoryCache(30)
+	c.Set("a", []byte("123456789")) // 10 bytes with the key
+	c.Set("b", []byte("123456789"))
+	c.Set("c", []byte("123456789"))
+	assert.EqualValues(t, 30, c.Size())
+	_, ok := c.Get("a") // a is now the most recently used
+	assert.True(t, ok)
+	c.Set("d", []byte("123456789"))
+	_, ok = c.Get("b")
+	assert.False(t, ok, "the least recently used value should be evicted")
+	for _, key := range []string{"a", "c", "d"} {
+		_, ok := c.Get(key)
+		assert.True(t, ok, "%v should still be cached", key)
+	}
+	assert.EqualValues(t, 30, c.Size())
+}
+
+func TestMemoryCacheReplacesAndDeletes(t *testing.T) {
+	c := NewMemoryCache(100)
+	c.Set("a", []byte("first"))
+	c.Set("a", []byte("second value"))
+	value, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, "second value", string(value))
+	assert.EqualValues(t, 13, c.Size(), "replaced values should not be counted")
+	c.Delete("a")
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.EqualValues(t, 0, c.Size())
+}
+
+func TestMemoryCacheDropsValuesLargerThanItsSize(t *testing.T) {
+	c := NewMemoryCache(10)
+	c.Set("a", []byte("1234"))
+	c.Set("b", []byte("12345678901234567890"))
+	_, ok := c.Get("b")
+	assert.False(t, ok, "values that don't fit should be dropped")
+	_, ok = c.Get("a")
+	assert.True(t, ok, "values that don't fit must not evict others")
+}
@@ -0,0 +1,32 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_cache` caches responses of outbound requests, following the HTTP caching rules of RFC 7234.
+
+The Tripperware serves fresh responses from the cache, and revalidates stale ones using their `ETag` and
+`Last-Modified` validators. The freshness of responses is based on their `Cache-Control` (`max-age`, `s-maxage`,
+`no-cache`, `no-store`, `must-revalidate`...) and `Expires` headers, and the `Cache-Control` of requests (`max-age`,
+`min-fresh`, `max-stale`, `no-cache`, `no-store`, `only-if-cached`) is honoured as well. Responses are only served for
+requests that match the request headers listed in their `Vary` header, and responses for different values of these
+headers are stored side by side. Responses with `Vary: *` or a `Set-Cookie` header are never stored.
+
+Successful `POST`, `PUT`, `PATCH` and `DELETE` requests invalidate the cached responses of their URL.
+
+Shared and private caches
+
+By default the Tripperware behaves as a shared cache, as the responses it caches are usually served to many different
+users of a service. It doesn't store responses marked as `private`, nor responses to requests with an `Authorization`
+header, unless the response explicitly allows it. Use `WithPrivateCache` if the cache is used on behalf of a single
+user only.
+
+Storage
+
+Responses are stored in a `Cache`, for which an in-memory LRU implementation bounded by size is provided by
+`NewMemoryCache`. Other implementations (e.g. memcached or Redis based) can be plugged in, as entries are stored as
+opaque byte slices.
+
+Whether a response came from the cache is set as the `http.cache.status` outbound tag of `http_ctxtags`, with values
+of "hit", "miss", "revalidated" and "bypass".
+*/
+package http_cache
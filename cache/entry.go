@@ -0,0 +1,163 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares/internal/header"
+)
+
+// entry is a stored response, encoded with gob in the Cache.
+type entry struct {
+	StatusCode   int
+	Header       http.Header
+	Body         []byte
+	RequestTime  time.Time
+	ResponseTime time.Time
+	// VaryHeader holds the values of the request headers listed in the `Vary` header of the response.
+	VaryHeader http.Header
+}
+
+func newEntry(req *http.Request, resp *http.Response, body []byte, requestTime time.Time, responseTime time.Time) *entry {
+	e := &entry{
+		StatusCode:   resp.StatusCode,
+		Header:       http_header.Clone(resp.Header),
+		Body:         body,
+		RequestTime:  requestTime,
+		ResponseTime: responseTime,
+		VaryHeader:   make(http.Header),
+	}
+	for _, field := range varyFields(resp.Header) {
+		e.VaryHeader[field] = req.Header[field]
+	}
+	return e
+}
+
+// maxVariants limits how many responses to a single URL are stored.
+const maxVariants = 16
+
+// variants are the stored responses to a URL, one for each value of the request headers listed in their `Vary` header.
+// They are encoded together with gob in the Cache, so that responses for different values of these headers don't
+// overwrite each other, and are invalidated together.
+type variants []*entry
+
+func decodeVariants(data []byte) (variants, error) {
+	var v variants
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v variants) encode() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// match returns the stored response matching the request, or nil if there is none.
+func (v variants) match(req *http.Request) *entry {
+	for _, e := range v {
+		if e.matches(req) {
+			return e
+		}
+	}
+	return nil
+}
+
+// with returns the variants with e replacing the response stored for the same request headers. Responses that vary on
+// other request headers than e are dropped, as the server changed what its responses vary on.
+func (v variants) with(e *entry) variants {
+	updated := variants{e}
+	for _, other := range v {
+		if len(updated) == maxVariants {
+			break
+		}
+		if other.sameVaryFields(e) && !other.sameVariant(e) {
+			updated = append(updated, other)
+		}
+	}
+	return updated
+}
+
+// matches checks whether the request headers listed in `Vary` are the same as those of the stored request.
+func (e *entry) matches(req *http.Request) bool {
+	for field, values := range e.VaryHeader {
+		if strings.Join(values, ",") != strings.Join(req.Header[field], ",") {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *entry) sameVaryFields(other *entry) bool {
+	if len(e.VaryHeader) != len(other.VaryHeader) {
+		return false
+	}
+	for field := range e.VaryHeader {
+		if _, ok := other.VaryHeader[field]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// sameVariant checks whether both responses were stored for the same values of the request headers listed in `Vary`.
+func (e *entry) sameVariant(other *entry) bool {
+	for field, values := range e.VaryHeader {
+		if strings.Join(values, ",") != strings.Join(other.VaryHeader[field], ",") {
+			return false
+		}
+	}
+	return e.sameVaryFields(other)
+}
+
+func (e *entry) date() time.Time {
+	if date, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return date
+	}
+	return e.ResponseTime
+}
+
+func (e *entry) hasValidators() bool {
+	return e.Header.Get("ETag") != "" || e.Header.Get("Last-Modified") != ""
+}
+
+// update freshens the stored response with the headers of a 304 response, see RFC 7234 section 4.3.4.
+func (e *entry) update(resp *http.Response, requestTime time.Time, responseTime time.Time) {
+	for k, v := range resp.Header {
+		if k != "Content-Length" {
+			e.Header[k] = v
+		}
+	}
+	e.RequestTime = requestTime
+	e.ResponseTime = responseTime
+}
+
+// response builds the response to the request out of the stored one.
+func (e *entry) response(req *http.Request, now time.Time) *http.Response {
+	header := http_header.Clone(e.Header)
+	header.Set("Age", strconv.FormatInt(int64(currentAge(e, now)/time.Second), 10))
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_cache
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxHeuristicFreshness limits the freshness of responses without explicit expiration, see RFC 7234 section 4.2.2.
+	maxHeuristicFreshness = 24 * time.Hour
+	maxDeltaSeconds       = 1<<31 - 1
+)
+
+// cacheControl holds the directives of `Cache-Control` headers.
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, value := range h["Cache-Control"] {
+		for _, part := range strings.Split(value, ",") {
+			name, arg := strings.TrimSpace(part), ""
+			if i := strings.Index(name, "="); i >= 0 {
+				name, arg = strings.TrimSpace(name[:i]), strings.Trim(strings.TrimSpace(name[i+1:]), `"`)
+			}
+			if name != "" {
+				cc[strings.ToLower(name)] = arg
+			}
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// duration returns the delta-seconds argument of the directive, or false if it is missing or malformed.
+func (cc cacheControl) duration(directive string) (time.Duration, bool) {
+	arg, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+	if seconds > maxDeltaSeconds {
+		seconds = maxDeltaSeconds
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+// freshnessLifetime returns how long a response stays fresh after it was generated, see RFC 7234 section 4.2.1.
+func freshnessLifetime(e *entry, shared bool) time.Duration {
+	cc := parseCacheControl(e.Header)
+	if shared {
+		if lifetime, ok := cc.duration("s-maxage"); ok {
+			return lifetime
+		}
+	}
+	if lifetime, ok := cc.duration("max-age"); ok {
+		return lifetime
+	}
+	date := e.date()
+	if expires := e.Header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil || !expiresAt.After(date) {
+			return 0 // invalid dates mean the response has already expired
+		}
+		return expiresAt.Sub(date)
+	}
+	if lastModified, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && cacheableByDefault(e.StatusCode) {
+		if date.After(lastModified) {
+			lifetime := date.Sub(lastModified) / 10
+			if lifetime > maxHeuristicFreshness {
+				lifetime = maxHeuristicFreshness
+			}
+			return lifetime
+		}
+	}
+	return 0
+}
+
+// currentAge returns the age of the response, see RFC 7234 section 4.2.3.
+func currentAge(e *entry, now time.Time) time.Duration {
+	apparentAge := e.ResponseTime.Sub(e.date())
+	if apparentAge < 0 {
+		apparentAge = 0
+	}
+	ageValue := time.Duration(0)
+	if seconds, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+	correctedAgeValue := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	correctedInitialAge := apparentAge
+	if correctedAgeValue > correctedInitialAge {
+		correctedInitialAge = correctedAgeValue
+	}
+	return correctedInitialAge + now.Sub(e.ResponseTime)
+}
+
+// canServeWithoutValidation decides whether the stored response can be used for the request as it is.
+func canServeWithoutValidation(e *entry, req *http.Request, reqCC cacheControl, now time.Time, shared bool) bool {
+	cc := parseCacheControl(e.Header)
+	if cc.has("no-cache") || reqCC.has("no-cache") {
+		return false
+	}
+	if len(req.Header["Cache-Control"]) == 0 && strings.Contains(strings.ToLower(req.Header.Get("Pragma")), "no-cache") {
+		return false
+	}
+	age := currentAge(e, now)
+	lifetime := freshnessLifetime(e, shared)
+	if maxAge, ok := reqCC.duration("max-age"); ok && age > maxAge {
+		return false
+	}
+	if minFresh, ok := reqCC.duration("min-fresh"); ok && lifetime-age < minFresh {
+		return false
+	}
+	if lifetime > age {
+		return true
+	}
+	if cc.has("must-revalidate") || (shared && (cc.has("proxy-revalidate") || cc.has("s-maxage"))) {
+		return false
+	}
+	if !reqCC.has("max-stale") {
+		return false
+	}
+	maxStale, ok := reqCC.duration("max-stale")
+	return !ok || age-lifetime <= maxStale // a max-stale without a value accepts any staleness
+}
+
+// isStorable decides whether the response to the request may be stored, see RFC 7234 section 3.
+func isStorable(req *http.Request, resp *http.Response, shared bool) bool {
+	if req.Method != "GET" || parseCacheControl(req.Header).has("no-store") {
+		return false
+	}
+	cc := parseCacheControl(resp.Header)
+	if cc.has("no-store") || (shared && cc.has("private")) {
+		return false
+	}
+	if shared && req.Header.Get("Authorization") != "" && !cc.has("must-revalidate") && !cc.has("public") && !cc.has("s-maxage") {
+		return false
+	}
+	if resp.Header.Get("Set-Cookie") != "" {
+		return false // the cookies are meant for the caller of this request only
+	}
+	for _, field := range varyFields(resp.Header) {
+		if field == "*" {
+			return false
+		}
+	}
+	explicit := resp.Header.Get("Expires") != "" || cc.has("max-age") || (shared && cc.has("s-maxage")) || cc.has("public")
+	if explicit {
+		return true
+	}
+	// Without explicit freshness, the response is only worth storing if it can be heuristically fresh or revalidated.
+	return cacheableByDefault(resp.StatusCode) && (resp.Header.Get("ETag") != "" || resp.Header.Get("Last-Modified") != "")
+}
+
+// cacheableByDefault lists the status codes that can be stored without explicit freshness, see RFC 7231 section 6.1.
+func cacheableByDefault(code int) bool {
+	switch code {
+	case 200, 203, 204, 300, 301, 404, 405, 410, 414, 501:
+		return true
+	}
+	return false
+}
+
+func varyFields(h http.Header) []string {
+	var fields []string
+	for _, value := range h["Vary"] {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, http.CanonicalHeaderKey(field))
+			}
+		}
+	}
+	return fields
+}
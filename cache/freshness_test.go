@@ -0,0 +1,138 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_cache
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var baseTime = time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func testEntry(headers ...string) *entry {
+	e := &entry{
+		StatusCode:   http.StatusOK,
+		Header:       http.Header{"Date": []string{baseTime.Format(http.TimeFormat)}},
+		RequestTime:  baseTime,
+		ResponseTime: baseTime,
+	}
+	for _, h := range headers {
+		parts := strings.SplitN(h, ": ", 2)
+		e.Header.Set(parts[0], parts[1])
+	}
+	return e
+}
+
+func testRequest(cacheControl string) (*http.Request, cacheControl) {
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	if cacheControl != "" {
+		req.Header.Set("Cache-Control", cacheControl)
+	}
+	return req, parseCacheControl(req.Header)
+}
+
+func TestParseCacheControl(t *testing.T) {
+	cc := parseCacheControl(http.Header{"Cache-Control": []string{`Max-Age=60, no-cache="Set-Cookie"`, "public"}})
+	assert.Equal(t, cacheControl{"max-age": "60", "no-cache": "Set-Cookie", "public": ""}, cc)
+	maxAge, ok := cc.duration("max-age")
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, maxAge)
+	_, ok = cacheControl{"max-age": "-1"}.duration("max-age")
+	assert.False(t, ok, "negative durations are malformed")
+}
+
+func TestFreshnessLifetime(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		entry   *entry
+		shared  time.Duration
+		private time.Duration
+	}{
+		{"none", testEntry(), 0, 0},
+		{"max-age", testEntry("Cache-Control: max-age=60"), time.Minute, time.Minute},
+		{"s-maxage", testEntry("Cache-Control: max-age=60, s-maxage=10"), 10 * time.Second, time.Minute},
+		{"expires", testEntry("Expires: " + baseTime.Add(time.Hour).Format(http.TimeFormat)), time.Hour, time.Hour},
+		{"invalid expires", testEntry("Expires: 0"), 0, 0},
+		{"max-age over expires", testEntry("Cache-Control: max-age=5", "Expires: "+baseTime.Add(time.Hour).Format(http.TimeFormat)), 5 * time.Second, 5 * time.Second},
+		{"heuristic", testEntry("Last-Modified: " + baseTime.Add(-10*time.Hour).Format(http.TimeFormat)), time.Hour, time.Hour},
+		{"capped heuristic", testEntry("Last-Modified: " + baseTime.Add(-1000*time.Hour).Format(http.TimeFormat)), 24 * time.Hour, 24 * time.Hour},
+	} {
+		assert.Equal(t, tc.shared, freshnessLifetime(tc.entry, true), "shared lifetime of %v", tc.name)
+		assert.Equal(t, tc.private, freshnessLifetime(tc.entry, false), "private lifetime of %v", tc.name)
+	}
+}
+
+func TestCurrentAge(t *testing.T) {
+	e := testEntry("Age: 30")
+	e.ResponseTime = baseTime.Add(2 * time.Second) // a slow response, generated at baseTime
+	e.RequestTime = baseTime
+	assert.Equal(t, 42*time.Second, currentAge(e, e.ResponseTime.Add(10*time.Second)), "age should add the Age header, the delay and the time in the cache")
+	e = testEntry()
+	e.Header.Set("Date", baseTime.Add(-time.Minute).Format(http.TimeFormat))
+	assert.Equal(t, time.Minute, currentAge(e, baseTime), "the apparent age should be used if it is larger")
+}
+
+func TestCanServeWithoutValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		entry        *entry
+		cacheControl string
+		elapsed      time.Duration
+		expected     bool
+	}{
+		{"fresh", testEntry("Cache-Control: max-age=60"), "", 30 * time.Second, true},
+		{"stale", testEntry("Cache-Control: max-age=60"), "", 90 * time.Second, false},
+		{"no-cache response", testEntry("Cache-Control: max-age=60, no-cache"), "", 0, false},
+		{"no-cache request", testEntry("Cache-Control: max-age=60"), "no-cache", 0, false},
+		{"max-age request", testEntry("Cache-Control: max-age=60"), "max-age=10", 30 * time.Second, false},
+		{"min-fresh request", testEntry("Cache-Control: max-age=60"), "min-fresh=40", 30 * time.Second, false},
+		{"max-stale request", testEntry("Cache-Control: max-age=60"), "max-stale=60", 90 * time.Second, true},
+		{"too stale request", testEntry("Cache-Control: max-age=60"), "max-stale=10", 90 * time.Second, false},
+		{"any stale request", testEntry("Cache-Control: max-age=60"), "max-stale", time.Hour, true},
+		{"must-revalidate", testEntry("Cache-Control: max-age=60, must-revalidate"), "max-stale", 90 * time.Second, false},
+	} {
+		req, reqCC := testRequest(tc.cacheControl)
+		assert.Equal(t, tc.expected, canServeWithoutValidation(tc.entry, req, reqCC, baseTime.Add(tc.elapsed), true), tc.name)
+	}
+}
+
+func TestIsStorable(t *testing.T) {
+	for _, tc := range []struct {
+		name          string
+		status        int
+		header        string
+		authorization bool
+		shared        bool
+		private       bool
+	}{
+		{"max-age", 200, "Cache-Control: max-age=60", false, true, true},
+		{"no freshness nor validators", 200, "Content-Type: text/plain", false, false, false},
+		{"validators", 200, "ETag: \"abc\"", false, true, true},
+		{"validators of a 500", 500, "ETag: \"abc\"", false, false, false},
+		{"explicit freshness of a 500", 500, "Cache-Control: max-age=60", false, true, true},
+		{"no-store", 200, "Cache-Control: max-age=60, no-store", false, false, false},
+		{"private", 200, "Cache-Control: max-age=60, private", false, false, true},
+		{"authorization", 200, "Cache-Control: max-age=60", true, false, true},
+		{"public authorization", 200, "Cache-Control: max-age=60, public", true, true, true},
+		{"vary all", 200, "Vary: *", false, false, false},
+		{"set-cookie", 200, "Set-Cookie: session=abc", false, false, false},
+	} {
+		req, _ := testRequest("")
+		if tc.authorization {
+			req.Header.Set("Authorization", "Bearer token")
+		}
+		parts := strings.SplitN(tc.header, ": ", 2)
+		resp := &http.Response{StatusCode: tc.status, Header: http.Header{}}
+		resp.Header.Set(parts[0], parts[1])
+		if tc.name == "vary all" || tc.name == "set-cookie" {
+			resp.Header.Set("Cache-Control", "max-age=60")
+		}
+		assert.Equal(t, tc.shared, isStorable(req, resp, true), "shared cache storing %v", tc.name)
+		assert.Equal(t, tc.private, isStorable(req, resp, false), "private cache storing %v", tc.name)
+	}
+}
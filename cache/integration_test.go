@@ -0,0 +1,196 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_cache_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/cache"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var lastModified = time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+
+// cacheableHandler sets caching headers based on the path, and counts the requests for each path.
+type cacheableHandler struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (h *cacheableHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	h.calls[req.URL.Path]++
+	call := h.calls[req.URL.Path]
+	h.mu.Unlock()
+	mode := strings.Split(strings.TrimPrefix(req.URL.Path, "/"), "/")[0]
+	switch mode {
+	case "max-age":
+		resp.Header().Set("Cache-Control", "max-age=60")
+	case "etag":
+		resp.Header().Set("Cache-Control", "max-age=0")
+		resp.Header().Set("ETag", `"v1"`)
+		if req.Header.Get("If-None-Match") == `"v1"` {
+			resp.Header().Set("x-revalidated", "true")
+			resp.WriteHeader(http.StatusNotModified)
+			return
+		}
+	case "last-modified":
+		resp.Header().Set("Cache-Control", "no-cache")
+		resp.Header().Set("Last-Modified", lastModified)
+		if req.Header.Get("If-Modified-Since") == lastModified {
+			resp.WriteHeader(http.StatusNotModified)
+			return
+		}
+	case "vary":
+		resp.Header().Set("Cache-Control", "max-age=60")
+		resp.Header().Set("Vary", "X-Tenant")
+	case "no-store":
+		resp.Header().Set("Cache-Control", "max-age=60, no-store")
+	}
+	if req.Method == "POST" {
+		resp.WriteHeader(http.StatusNoContent)
+		return
+	}
+	resp.Header().Set("Content-Type", "text/plain")
+	resp.WriteHeader(http.StatusOK)
+	fmt.Fprintf(resp, "call %d to %s", call, req.URL.Path)
+}
+
+func (h *cacheableHandler) callCount(path string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.calls[path]
+}
+
+func TestCacheSuite(t *testing.T) {
+	handler := &cacheableHandler{calls: make(map[string]int)}
+	s := &CacheSuite{
+		handler: handler,
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: handler,
+			ClientTripperware: []httpwares.Tripperware{
+				http_ctxtags.Tripperware(),
+				http_cache.Tripperware(http_cache.NewMemoryCache(1 << 20)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type CacheSuite struct {
+	*httpwares_testing.WaresTestSuite
+	handler *cacheableHandler
+}
+
+func (s *CacheSuite) call(method string, path string, header ...string) (*http.Response, string, string) {
+	req, _ := http.NewRequest(method, "https://something.local"+path, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(s.T(), err, "reading the body shouldn't fail")
+	status, _ := http_ctxtags.ExtractOutbound(resp.Request).Values()[http_cache.TagForCacheStatus].(string)
+	return resp, string(body), status
+}
+
+func (s *CacheSuite) TestFreshResponsesAreServedFromCache() {
+	_, first, status := s.call("GET", "/max-age/fresh")
+	assert.Equal(s.T(), http_cache.CacheStatusMiss, status)
+	resp, second, status := s.call("GET", "/max-age/fresh")
+	assert.Equal(s.T(), http_cache.CacheStatusHit, status)
+	assert.Equal(s.T(), first, second, "the cached response should be returned")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), "0", resp.Header.Get("Age"), "the age of the response should be set")
+	assert.Equal(s.T(), 1, s.handler.callCount("/max-age/fresh"), "the server should only be called once")
+}
+
+func (s *CacheSuite) TestStaleResponsesAreRevalidatedWithETag() {
+	_, first, _ := s.call("GET", "/etag/revalidate")
+	resp, second, status := s.call("GET", "/etag/revalidate")
+	assert.Equal(s.T(), http_cache.CacheStatusRevalidated, status)
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode, "the caller should get the full response")
+	assert.Equal(s.T(), first, second, "the cached body should be returned")
+	assert.Equal(s.T(), "true", resp.Header.Get("x-revalidated"), "headers of the 304 should update the cached ones")
+	assert.Equal(s.T(), 2, s.handler.callCount("/etag/revalidate"))
+}
+
+func (s *CacheSuite) TestNoCacheResponsesAreRevalidatedWithLastModified() {
+	_, first, _ := s.call("GET", "/last-modified/revalidate")
+	_, second, status := s.call("GET", "/last-modified/revalidate")
+	assert.Equal(s.T(), http_cache.CacheStatusRevalidated, status)
+	assert.Equal(s.T(), first, second, "the cached body should be returned")
+}
+
+func (s *CacheSuite) TestVaryingRequestHeadersAreNotServedFromCache() {
+	_, first, _ := s.call("GET", "/vary/tenants", "X-Tenant", "a")
+	_, second, status := s.call("GET", "/vary/tenants", "X-Tenant", "b")
+	assert.Equal(s.T(), http_cache.CacheStatusMiss, status, "a different tenant should not get the cached response")
+	assert.NotEqual(s.T(), first, second)
+	_, third, status := s.call("GET", "/vary/tenants", "X-Tenant", "b")
+	assert.Equal(s.T(), http_cache.CacheStatusHit, status, "the same tenant should get the cached response")
+	assert.Equal(s.T(), second, third)
+}
+
+func (s *CacheSuite) TestResponsesVaryingOnRequestHeadersAreStoredSideBySide() {
+	_, first, _ := s.call("GET", "/vary/side-by-side", "X-Tenant", "a")
+	_, second, _ := s.call("GET", "/vary/side-by-side", "X-Tenant", "b")
+	_, body, status := s.call("GET", "/vary/side-by-side", "X-Tenant", "a")
+	assert.Equal(s.T(), http_cache.CacheStatusHit, status, "storing the response of another tenant shouldn't evict the first one")
+	assert.Equal(s.T(), first, body)
+	_, body, status = s.call("GET", "/vary/side-by-side", "X-Tenant", "b")
+	assert.Equal(s.T(), http_cache.CacheStatusHit, status)
+	assert.Equal(s.T(), second, body)
+	assert.Equal(s.T(), 2, s.handler.callCount("/vary/side-by-side"))
+}
+
+func (s *CacheSuite) TestNoStoreResponsesAreNotCached() {
+	s.call("GET", "/no-store/never")
+	_, _, status := s.call("GET", "/no-store/never")
+	assert.Equal(s.T(), http_cache.CacheStatusMiss, status)
+	assert.Equal(s.T(), 2, s.handler.callCount("/no-store/never"))
+}
+
+func (s *CacheSuite) TestRequestNoCacheSkipsFreshResponses() {
+	s.call("GET", "/max-age/no-cache")
+	_, _, status := s.call("GET", "/max-age/no-cache", "Cache-Control", "no-cache")
+	assert.Equal(s.T(), http_cache.CacheStatusMiss, status, "the server should be asked again")
+	assert.Equal(s.T(), 2, s.handler.callCount("/max-age/no-cache"))
+}
+
+func (s *CacheSuite) TestUnsafeRequestsInvalidateTheCache() {
+	s.call("GET", "/max-age/invalidated")
+	_, _, status := s.call("POST", "/max-age/invalidated")
+	assert.Equal(s.T(), http_cache.CacheStatusBypass, status)
+	_, body, status := s.call("GET", "/max-age/invalidated")
+	assert.Equal(s.T(), http_cache.CacheStatusMiss, status, "the cached response should have been invalidated")
+	assert.Equal(s.T(), "call 3 to /max-age/invalidated", body)
+}
+
+func (s *CacheSuite) TestOnlyIfCachedWithoutCachedResponse() {
+	resp, _, status := s.call("GET", "/max-age/only-if-cached", "Cache-Control", "only-if-cached")
+	assert.Equal(s.T(), http_cache.CacheStatusMiss, status)
+	assert.Equal(s.T(), http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Equal(s.T(), 0, s.handler.callCount("/max-age/only-if-cached"), "the server must not be called")
+}
+
+func (s *CacheSuite) TestCallerConditionalRequestsBypassTheCache() {
+	s.call("GET", "/etag/conditional")
+	resp, _, status := s.call("GET", "/etag/conditional", "If-None-Match", `"v1"`)
+	assert.Equal(s.T(), http_cache.CacheStatusBypass, status)
+	assert.Equal(s.T(), http.StatusNotModified, resp.StatusCode, "the caller should get the 304 it asked for")
+}
@@ -0,0 +1,48 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_cache
+
+import "time"
+
+var (
+	defaultOptions = &options{
+		shared:      true,
+		maxBodySize: 1 << 20,
+		clock:       time.Now,
+	}
+)
+
+type options struct {
+	shared      bool
+	maxBodySize int64
+	clock       func() time.Time
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithPrivateCache makes the cache behave as a private one, i.e. one used on behalf of a single user.
+//
+// Private caches store responses marked as `private` and responses to requests with an `Authorization` header, and
+// ignore the `s-maxage` and `proxy-revalidate` directives.
+func WithPrivateCache() Option {
+	return func(o *options) {
+		o.shared = false
+	}
+}
+
+// WithMaxBodySize sets the size in bytes of the largest response body that is stored. The default is 1MB.
+func WithMaxBodySize(bytes int64) Option {
+	return func(o *options) {
+		o.maxBodySize = bytes
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_cache
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/internal/header"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+const (
+	// TagForCacheStatus is the outbound tag holding how the request was served by the cache.
+	TagForCacheStatus = "http.cache.status"
+
+	// CacheStatusHit means that the response was served from the cache, without contacting the server.
+	CacheStatusHit = "hit"
+	// CacheStatusMiss means that the response was fetched from the server, as it wasn't cached or was stale.
+	CacheStatusMiss = "miss"
+	// CacheStatusRevalidated means that the server confirmed a stale cached response to be still valid.
+	CacheStatusRevalidated = "revalidated"
+	// CacheStatusBypass means that the request wasn't cacheable (e.g. a POST, or had `Cache-Control: no-store`).
+	CacheStatusBypass = "bypass"
+)
+
+// Tripperware returns a new client-side ware that caches responses in the given Cache.
+//
+// Requests with their own conditional (`If-None-Match`, `If-Modified-Since`) or `Range` headers bypass the cache, as
+// the caller is expected to deal with the response itself.
+//
+// Responses are stored once their body is read to the end, and only if it is smaller than the maximum body size (see
+// `WithMaxBodySize`).
+func Tripperware(cache Cache, opts ...Option) httpwares.Tripperware {
+	o := evaluateOptions(opts)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			tags := http_ctxtags.ExtractOutbound(req)
+			if req.Method != "GET" {
+				tags.Set(TagForCacheStatus, CacheStatusBypass)
+				resp, err := next.RoundTrip(req)
+				if err == nil && isUnsafe(req.Method) && resp.StatusCode < 400 {
+					invalidate(cache, req, resp)
+				}
+				return resp, err
+			}
+			reqCC := parseCacheControl(req.Header)
+			if reqCC.has("no-store") || req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != "" || req.Header.Get("Range") != "" {
+				tags.Set(TagForCacheStatus, CacheStatusBypass)
+				return next.RoundTrip(req)
+			}
+			key := cacheKey(req.URL)
+			e := load(cache, key, req)
+			if e != nil && canServeWithoutValidation(e, req, reqCC, o.clock(), o.shared) {
+				tags.Set(TagForCacheStatus, CacheStatusHit)
+				return e.response(req, o.clock()), nil
+			}
+			if reqCC.has("only-if-cached") {
+				tags.Set(TagForCacheStatus, CacheStatusMiss)
+				return gatewayTimeout(req), nil
+			}
+			outReq := req
+			if e != nil && e.hasValidators() {
+				outReq = conditionalRequest(req, e)
+			}
+			requestTime := o.clock()
+			resp, err := next.RoundTrip(outReq)
+			if err != nil {
+				tags.Set(TagForCacheStatus, CacheStatusMiss)
+				return nil, err
+			}
+			responseTime := o.clock()
+			if outReq != req && resp.StatusCode == http.StatusNotModified {
+				discardBody(resp)
+				e.update(resp, requestTime, responseTime)
+				store(cache, key, e)
+				tags.Set(TagForCacheStatus, CacheStatusRevalidated)
+				return e.response(req, responseTime), nil
+			}
+			tags.Set(TagForCacheStatus, CacheStatusMiss)
+			if outReq != req {
+				resp.Request = req
+			}
+			if !isStorable(req, resp, o.shared) {
+				if parseCacheControl(resp.Header).has("no-store") {
+					cache.Delete(key)
+				}
+				return resp, nil
+			}
+			resp.Body = &storingBody{
+				ReadCloser: resp.Body,
+				maxSize:    o.maxBodySize,
+				onEOF: func(body []byte) {
+					store(cache, key, newEntry(req, resp, body, requestTime, responseTime))
+				},
+			}
+			return resp, nil
+		})
+	}
+}
+
+func cacheKey(u *url.URL) string {
+	keyURL := *u
+	keyURL.Fragment = ""
+	return keyURL.String()
+}
+
+// load returns the stored response matching the request, or nil if there is none.
+func load(cache Cache, key string, req *http.Request) *entry {
+	v, ok := loadVariants(cache, key)
+	if !ok {
+		return nil
+	}
+	return v.match(req)
+}
+
+// store adds the response to the ones stored for the URL, replacing the one stored for the same request headers.
+func store(cache Cache, key string, e *entry) {
+	v, _ := loadVariants(cache, key)
+	if data, err := v.with(e).encode(); err == nil {
+		cache.Set(key, data)
+	}
+}
+
+func loadVariants(cache Cache, key string) (variants, bool) {
+	data, ok := cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	v, err := decodeVariants(data)
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
+
+func isUnsafe(method string) bool {
+	switch method {
+	case "POST", "PUT", "PATCH", "DELETE":
+		return true
+	}
+	return false
+}
+
+// invalidate removes the responses of the URLs changed by an unsafe request, see RFC 7234 section 4.4.
+func invalidate(cache Cache, req *http.Request, resp *http.Response) {
+	cache.Delete(cacheKey(req.URL))
+	for _, header := range []string{"Location", "Content-Location"} {
+		location := resp.Header.Get(header)
+		if location == "" {
+			continue
+		}
+		if u, err := req.URL.Parse(location); err == nil && u.Host == req.URL.Host {
+			cache.Delete(cacheKey(u))
+		}
+	}
+}
+
+func conditionalRequest(req *http.Request, e *entry) *http.Request {
+	condReq := http_header.CloneRequestWithHeader(req)
+	if etag := e.Header.Get("ETag"); etag != "" {
+		condReq.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := e.Header.Get("Last-Modified"); lastModified != "" {
+		condReq.Header.Set("If-Modified-Since", lastModified)
+	}
+	return condReq
+}
+
+// gatewayTimeout is the response to `only-if-cached` requests that can't be served from the cache.
+func gatewayTimeout(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:     "504 " + http.StatusText(http.StatusGatewayTimeout),
+		StatusCode: http.StatusGatewayTimeout,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}
+}
+
+func discardBody(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+}
+
+// storingBody keeps a copy of the body as it is read, and passes it to onEOF once it is read to the end.
+type storingBody struct {
+	io.ReadCloser
+	buf      bytes.Buffer
+	maxSize  int64
+	tooLarge bool
+	onEOF    func(body []byte)
+}
+
+func (b *storingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.tooLarge {
+		b.buf.Write(p[:n])
+		if int64(b.buf.Len()) > b.maxSize {
+			b.tooLarge = true
+			b.buf = bytes.Buffer{}
+		}
+	}
+	if err == io.EOF && !b.tooLarge && b.onEOF != nil {
+		b.onEOF(b.buf.Bytes())
+		b.onEOF = nil
+	}
+	return n, err
+}
@@ -0,0 +1,168 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_circuitbreaker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// State is the state of the circuit breaker of a service.
+type State int
+
+const (
+	// StateClosed lets all requests through.
+	StateClosed State = iota
+	// StateOpen fails all requests fast.
+	StateOpen
+	// StateHalfOpen lets a limited number of trial requests through.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// OpenCircuitError is returned by the Tripperware for requests that were not sent because the breaker of the service
+// is open, or half-open with all trial requests already in flight.
+type OpenCircuitError struct {
+	Service string
+	State   State
+}
+
+func (e *OpenCircuitError) Error() string {
+	return fmt.Sprintf("circuit breaker for service %q is %v", e.Service, e.State)
+}
+
+type stateChange struct {
+	from State
+	to   State
+}
+
+// breaker is the state machine of a single service.
+//
+// Every state change starts a new generation, and the results of requests admitted in a previous generation are
+// ignored.
+type breaker struct {
+	mu         sync.Mutex
+	service    string
+	opts       *options
+	clock      func() time.Time
+	state      State
+	generation uint64
+	expiry     time.Time
+	admitted   uint
+	completed  uint
+	failures   uint
+	successes  uint
+}
+
+func newBreaker(service string, opts *options, clock func() time.Time) *breaker {
+	b := &breaker{service: service, opts: opts, clock: clock}
+	b.newGeneration(clock())
+	return b
+}
+
+// allow admits a request, returning the generation it belongs to.
+func (b *breaker) allow() (uint64, State, error) {
+	b.mu.Lock()
+	changes := b.refresh(b.clock())
+	state, generation := b.state, b.generation
+	var err error
+	if state == StateOpen || (state == StateHalfOpen && b.admitted >= b.opts.halfOpenRequests) {
+		err = &OpenCircuitError{Service: b.service, State: state}
+	} else {
+		b.admitted++
+	}
+	b.mu.Unlock()
+	b.notify(changes)
+	return generation, state, err
+}
+
+// done records the outcome of an admitted request.
+func (b *breaker) done(generation uint64, failed bool) {
+	b.mu.Lock()
+	changes := b.refresh(b.clock())
+	if generation == b.generation {
+		switch b.state {
+		case StateClosed:
+			b.completed++
+			if failed {
+				b.failures++
+			}
+			if b.completed >= b.opts.minimumRequests && float64(b.failures) >= b.opts.failureRatio*float64(b.completed) {
+				changes = append(changes, b.setState(StateOpen))
+			}
+		case StateHalfOpen:
+			if failed {
+				changes = append(changes, b.setState(StateOpen))
+			} else if b.successes++; b.successes >= b.opts.halfOpenRequests {
+				changes = append(changes, b.setState(StateClosed))
+			}
+		}
+	}
+	b.mu.Unlock()
+	b.notify(changes)
+}
+
+// release gives back the slot of an admitted request whose outcome says nothing about the service.
+func (b *breaker) release(generation uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if generation == b.generation && b.admitted > 0 {
+		b.admitted--
+	}
+}
+
+// refresh moves the breaker to a new generation if the current one expired.
+func (b *breaker) refresh(now time.Time) []stateChange {
+	if now.Before(b.expiry) {
+		return nil
+	}
+	switch b.state {
+	case StateClosed:
+		b.newGeneration(now)
+	case StateOpen:
+		return []stateChange{b.setState(StateHalfOpen)}
+	}
+	return nil
+}
+
+func (b *breaker) setState(state State) stateChange {
+	change := stateChange{from: b.state, to: state}
+	b.state = state
+	b.newGeneration(b.clock())
+	return change
+}
+
+func (b *breaker) newGeneration(now time.Time) {
+	b.generation++
+	b.admitted, b.completed, b.failures, b.successes = 0, 0, 0, 0
+	switch b.state {
+	case StateClosed:
+		b.expiry = now.Add(b.opts.window)
+	case StateOpen:
+		b.expiry = now.Add(b.opts.coolDown)
+	case StateHalfOpen:
+		b.expiry = time.Time{} // only ends with the outcome of the trial requests
+	}
+}
+
+func (b *breaker) notify(changes []stateChange) {
+	if b.opts.onStateChange == nil {
+		return
+	}
+	for _, c := range changes {
+		b.opts.onStateChange(b.service, c.from, c.to)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_circuitbreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func newTestBreaker(opts ...Option) (*breaker, *fakeClock, *[]stateChange) {
+	var changes []stateChange
+	opts = append([]Option{
+		WithMinimumRequests(4),
+		WithFailureRatio(0.5),
+		WithWindow(10 * time.Second),
+		WithCoolDown(5 * time.Second),
+		WithHalfOpenRequests(2),
+		WithStateChangeCallback(func(service string, from State, to State) {
+			changes = append(changes, stateChange{from: from, to: to})
+		}),
+	}, opts...)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	return newBreaker("svc", evaluateOptions(opts), clock.Now), clock, &changes
+}
+
+func call(t *testing.T, b *breaker, failed bool) {
+	generation, _, err := b.allow()
+	require.NoError(t, err, "request should be allowed")
+	b.done(generation, failed)
+}
+
+func TestBreakerOpensOnFailureRatio(t *testing.T) {
+	b, _, changes := newTestBreaker()
+	call(t, b, true)
+	call(t, b, true)
+	call(t, b, false)
+	assert.Equal(t, StateClosed, b.state, "breaker shouldn't open below the minimum requests")
+	call(t, b, false)
+	assert.Equal(t, StateOpen, b.state, "breaker should open at a failure ratio of 0.5")
+	_, state, err := b.allow()
+	require.Error(t, err, "requests should fail fast when open")
+	assert.Equal(t, StateOpen, state, "state should be reported")
+	assert.Equal(t, &OpenCircuitError{Service: "svc", State: StateOpen}, err, "error should be typed")
+	assert.Equal(t, []stateChange{{StateClosed, StateOpen}}, *changes, "state change should be reported")
+}
+
+func TestBreakerOpensAtExactlyTheFailureRatio(t *testing.T) {
+	b, _, _ := newTestBreaker(WithFailureRatio(0.75))
+	call(t, b, true)
+	call(t, b, true)
+	call(t, b, false)
+	call(t, b, false)
+	assert.Equal(t, StateClosed, b.state, "breaker shouldn't open below the failure ratio")
+	b, _, _ = newTestBreaker(WithFailureRatio(0.75))
+	call(t, b, true)
+	call(t, b, true)
+	call(t, b, true)
+	call(t, b, false)
+	assert.Equal(t, StateOpen, b.state, "breaker should open at exactly the failure ratio")
+}
+
+func TestBreakerCountsResetWithWindow(t *testing.T) {
+	b, clock, _ := newTestBreaker()
+	call(t, b, true)
+	call(t, b, true)
+	call(t, b, true)
+	clock.now = clock.now.Add(11 * time.Second)
+	call(t, b, true)
+	assert.Equal(t, StateClosed, b.state, "failures from the previous window shouldn't count")
+}
+
+func TestBreakerHalfOpenCloses(t *testing.T) {
+	b, clock, changes := newTestBreaker(WithMinimumRequests(1))
+	call(t, b, true)
+	require.Equal(t, StateOpen, b.state, "breaker should open")
+	clock.now = clock.now.Add(5 * time.Second)
+	first, state, err := b.allow()
+	require.NoError(t, err, "first trial request should be allowed")
+	assert.Equal(t, StateHalfOpen, state, "breaker should be half-open after the cool-down")
+	second, _, err := b.allow()
+	require.NoError(t, err, "second trial request should be allowed")
+	_, _, err = b.allow()
+	require.Error(t, err, "only two trial requests should be allowed")
+	b.done(first, false)
+	assert.Equal(t, StateHalfOpen, b.state, "breaker should wait for all trial requests")
+	b.done(second, false)
+	assert.Equal(t, StateClosed, b.state, "breaker should close after successful trials")
+	assert.Equal(t, []stateChange{{StateClosed, StateOpen}, {StateOpen, StateHalfOpen}, {StateHalfOpen, StateClosed}}, *changes, "state changes should be reported")
+}
+
+func TestBreakerHalfOpenReopens(t *testing.T) {
+	b, clock, _ := newTestBreaker(WithMinimumRequests(1))
+	call(t, b, true)
+	clock.now = clock.now.Add(5 * time.Second)
+	call(t, b, true)
+	assert.Equal(t, StateOpen, b.state, "breaker should open again after a failed trial")
+	clock.now = clock.now.Add(4 * time.Second)
+	_, _, err := b.allow()
+	assert.Error(t, err, "the cool-down should start over")
+}
+
+func TestBreakerIgnoresStaleGenerations(t *testing.T) {
+	b, clock, _ := newTestBreaker(WithMinimumRequests(1))
+	stale, _, err := b.allow()
+	require.NoError(t, err, "request should be allowed")
+	call(t, b, true)
+	clock.now = clock.now.Add(5 * time.Second)
+	trial, _, err := b.allow()
+	require.NoError(t, err, "trial request should be allowed")
+	b.done(stale, true)
+	assert.Equal(t, StateHalfOpen, b.state, "results from before opening shouldn't count")
+	b.release(trial)
+	_, _, err = b.allow()
+	assert.NoError(t, err, "released trial slots should be reusable")
+}
@@ -0,0 +1,22 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_circuitbreaker` is a HTTP client-side Tripperware that stops calling services that keep failing.
+
+Circuit Breaking
+
+A separate circuit breaker is kept for each service called, as identified by the `http.call.service` tag of
+`http_ctxtags`. Each breaker starts closed, letting all requests through and counting their failures over a window of
+time. Once enough requests have been seen and the ratio of failures is too high, the breaker opens and requests to the
+service fail fast with an `*OpenCircuitError`, without being sent.
+
+After a cool-down period the breaker becomes half-open, and lets a limited number of trial requests through. If they
+all succeed, the breaker closes again, otherwise it goes back to being open.
+
+Reporting
+
+The state of the breaker at the time of the request is set as the `http.circuitbreaker.state` tag, so that logging and
+metrics wares placed after this one can report it. State changes can also be observed using `WithStateChangeCallback`.
+*/
+package http_circuitbreaker
@@ -0,0 +1,130 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_circuitbreaker_test
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/circuitbreaker"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	coolDown = 100 * time.Millisecond
+)
+
+type countingHandler struct {
+	mu       sync.Mutex
+	reqCount uint
+}
+
+func (h *countingHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	h.reqCount += 1
+	h.mu.Unlock()
+	httpwares_testing.PingBackHandler(httpwares_testing.DefaultPingBackStatusCode).ServeHTTP(resp, req)
+}
+
+func (h *countingHandler) requestCount() uint {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.reqCount
+}
+
+type stateChange struct {
+	service string
+	from    http_circuitbreaker.State
+	to      http_circuitbreaker.State
+}
+
+func TestCircuitBreakerSuite(t *testing.T) {
+	s := &CircuitBreakerSuite{handler: &countingHandler{}}
+	s.WaresTestSuite = &httpwares_testing.WaresTestSuite{
+		Handler: s.handler,
+		ClientTripperware: []httpwares.Tripperware{
+			http_ctxtags.Tripperware(http_ctxtags.WithServiceNameDetector(func(req *http.Request) string {
+				return req.URL.Query().Get("service")
+			})),
+			http_circuitbreaker.Tripperware(
+				http_circuitbreaker.WithMinimumRequests(4),
+				http_circuitbreaker.WithFailureRatio(0.5),
+				http_circuitbreaker.WithCoolDown(coolDown),
+				http_circuitbreaker.WithStateChangeCallback(s.recordStateChange),
+			),
+		},
+	}
+	suite.Run(t, s)
+}
+
+type CircuitBreakerSuite struct {
+	*httpwares_testing.WaresTestSuite
+	handler *countingHandler
+	mu      sync.Mutex
+	changes []stateChange
+}
+
+func (s *CircuitBreakerSuite) recordStateChange(service string, from http_circuitbreaker.State, to http_circuitbreaker.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, stateChange{service: service, from: from, to: to})
+}
+
+func (s *CircuitBreakerSuite) stateChanges() []stateChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]stateChange(nil), s.changes...)
+}
+
+func (s *CircuitBreakerSuite) call(service string, code int) (*http.Response, error) {
+	query := url.Values{"service": []string{service}, "code": []string{strconv.Itoa(code)}}
+	req, _ := http.NewRequest("GET", "https://something.local/someurl?"+query.Encode(), nil)
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	if err == nil {
+		resp.Body.Close()
+	}
+	return resp, err
+}
+
+func (s *CircuitBreakerSuite) TestOpensAndClosesPerService() {
+	for _, code := range []int{500, 200, 503, 201} {
+		_, err := s.call("failing", code)
+		require.NoError(s.T(), err, "calls should go through while the breaker is closed")
+	}
+	_, err := s.call("healthy", 200)
+	require.NoError(s.T(), err, "breakers of other services should be unaffected")
+	countBefore := s.handler.requestCount()
+
+	_, err = s.call("failing", 200)
+	require.Error(s.T(), err, "the breaker should be open")
+	urlErr, ok := err.(*url.Error)
+	require.True(s.T(), ok, "client errors should be url.Errors")
+	openErr, ok := urlErr.Err.(*http_circuitbreaker.OpenCircuitError)
+	require.True(s.T(), ok, "open circuit should fail with an OpenCircuitError, got %v", urlErr.Err)
+	assert.Equal(s.T(), "failing", openErr.Service, "error should name the service")
+	assert.Equal(s.T(), countBefore, s.handler.requestCount(), "the request shouldn't reach the server")
+
+	time.Sleep(coolDown)
+	resp, err := s.call("failing", 200)
+	require.NoError(s.T(), err, "the trial request should go through after the cool-down")
+	assert.Equal(s.T(), "half-open", http_ctxtags.ExtractOutbound(resp.Request).Values()[http_circuitbreaker.TagForState], "breaker state should be tagged")
+	resp, err = s.call("failing", 200)
+	require.NoError(s.T(), err, "the breaker should be closed after a successful trial")
+	assert.Equal(s.T(), "closed", http_ctxtags.ExtractOutbound(resp.Request).Values()[http_circuitbreaker.TagForState], "breaker state should be tagged")
+
+	assert.Equal(s.T(), []stateChange{
+		{"failing", http_circuitbreaker.StateClosed, http_circuitbreaker.StateOpen},
+		{"failing", http_circuitbreaker.StateOpen, http_circuitbreaker.StateHalfOpen},
+		{"failing", http_circuitbreaker.StateHalfOpen, http_circuitbreaker.StateClosed},
+	}, s.stateChanges(), "state changes should be reported")
+}
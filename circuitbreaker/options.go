@@ -0,0 +1,111 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_circuitbreaker
+
+import (
+	"net/http"
+	"time"
+)
+
+var (
+	defaultOptions = &options{
+		failureRatio:     0.5,
+		minimumRequests:  20,
+		window:           10 * time.Second,
+		coolDown:         5 * time.Second,
+		halfOpenRequests: 1,
+		failureDecider:   DefaultFailureDecider,
+	}
+)
+
+type options struct {
+	failureRatio     float64
+	minimumRequests  uint
+	window           time.Duration
+	coolDown         time.Duration
+	halfOpenRequests uint
+	failureDecider   FailureDeciderFunc
+	onStateChange    StateChangeFunc
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// FailureDeciderFunc decides whether the result of a request counts as a failure of the service.
+type FailureDeciderFunc func(resp *http.Response, err error) bool
+
+// StateChangeFunc is called whenever the breaker of a service changes its state.
+type StateChangeFunc func(service string, from State, to State)
+
+// WithFailureRatio sets the ratio (between 0 and 1) of failed requests at or above which the breaker opens.
+//
+// By default the breaker opens once half of the requests fail.
+func WithFailureRatio(ratio float64) Option {
+	return func(o *options) {
+		o.failureRatio = ratio
+	}
+}
+
+// WithMinimumRequests sets the number of requests that need to be seen within the window before the breaker can open.
+//
+// This prevents a handful of failures on a quiet service from opening the breaker. The default is 20.
+func WithMinimumRequests(requests uint) Option {
+	return func(o *options) {
+		o.minimumRequests = requests
+	}
+}
+
+// WithWindow sets the period of time over which requests and failures are counted while the breaker is closed.
+//
+// The counts are reset at the end of every window. The default is 10 seconds.
+func WithWindow(window time.Duration) Option {
+	return func(o *options) {
+		o.window = window
+	}
+}
+
+// WithCoolDown sets how long the breaker stays open before letting trial requests through. The default is 5 seconds.
+func WithCoolDown(coolDown time.Duration) Option {
+	return func(o *options) {
+		o.coolDown = coolDown
+	}
+}
+
+// WithHalfOpenRequests sets the number of trial requests let through when the breaker is half-open.
+//
+// All of them need to succeed for the breaker to close. The default is 1.
+func WithHalfOpenRequests(requests uint) Option {
+	return func(o *options) {
+		o.halfOpenRequests = requests
+	}
+}
+
+// WithFailureDecider customizes what is considered to be a failure of the service.
+func WithFailureDecider(f FailureDeciderFunc) Option {
+	return func(o *options) {
+		o.failureDecider = f
+	}
+}
+
+// WithStateChangeCallback sets a function that is called whenever a breaker changes its state.
+//
+// The function is called synchronously, on the goroutine of the request that caused the change.
+func WithStateChangeCallback(f StateChangeFunc) Option {
+	return func(o *options) {
+		o.onStateChange = f
+	}
+}
+
+// DefaultFailureDecider is the default implementation that treats errors and 5xx responses as failures.
+func DefaultFailureDecider(resp *http.Response, err error) bool {
+	return err != nil || resp.StatusCode >= 500
+}
@@ -0,0 +1,71 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_circuitbreaker
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// TagForState is the outbound tag holding the state of the breaker (e.g. "closed") at the time of the request.
+const TagForState = "http.circuitbreaker.state"
+
+// Tripperware is client side HTTP ware that fails requests fast when the called service keeps failing.
+//
+// Breakers are kept per service name, as set by the `http_ctxtags.Tripperware`, which should be placed before this
+// one in the chain. Requests without a service tag use the `http_ctxtags.DefaultServiceNameDetector`.
+//
+// The breakers are shared by all the transports wrapped with the returned Tripperware.
+//
+// Requests whose context is done before they finish are not counted as either successes or failures.
+func Tripperware(opts ...Option) httpwares.Tripperware {
+	o := evaluateOptions(opts)
+	breakers := &breakerSet{opts: o, breakers: make(map[string]*breaker)}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			tags := http_ctxtags.ExtractOutbound(req)
+			b := breakers.get(serviceName(req, tags))
+			generation, state, err := b.allow()
+			tags.Set(TagForState, state.String())
+			if err != nil {
+				return nil, err
+			}
+			resp, err := next.RoundTrip(req)
+			if req.Context().Err() != nil {
+				b.release(generation)
+			} else {
+				b.done(generation, o.failureDecider(resp, err))
+			}
+			return resp, err
+		})
+	}
+}
+
+func serviceName(req *http.Request, tags *http_ctxtags.Tags) string {
+	if svc, ok := tags.Values()[http_ctxtags.TagForCallService].(string); ok {
+		return svc
+	}
+	return http_ctxtags.DefaultServiceNameDetector(req)
+}
+
+type breakerSet struct {
+	mu       sync.Mutex
+	opts     *options
+	breakers map[string]*breaker
+}
+
+func (s *breakerSet) get(service string) *breaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.breakers[service]
+	if !ok {
+		b = newBreaker(service, s.opts, time.Now)
+		s.breakers[service] = b
+	}
+	return b
+}
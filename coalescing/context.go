@@ -0,0 +1,31 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_coalescing
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext keeps the values (e.g. tags and tracing spans) of a caller's context, but not its cancellation, so
+// that the shared request outlives the caller that happened to start it.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,18 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_coalescing` coalesces concurrent identical requests into a single one.
+
+When many handlers ask for the same resource of an upstream service at the same time, the Tripperware sends a single
+request, and fans its response out to all the callers. Each of them gets its own copy of the response, with a body
+that can be read independently of the others.
+
+Only `GET` and `HEAD` requests without a body are coalesced. Requests are identical if they have the same method, URL,
+and values of a set of headers (by default the ones that usually change the response: `Accept`, `Accept-Encoding`,
+`Authorization` and `Cookie`, see `WithKeyHeaders`).
+
+A caller whose context is done stops waiting for the response straight away. The shared request is only cancelled
+once all of its callers stopped waiting for it.
+*/
+package http_coalescing
@@ -0,0 +1,203 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_coalescing_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/coalescing"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// blockingHandler holds all requests until released, and counts how many of them it got.
+type blockingHandler struct {
+	mu        sync.Mutex
+	release   chan struct{}
+	calls     int32
+	cancelled chan struct{}
+	body      string
+}
+
+func (h *blockingHandler) reset(body string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.release = make(chan struct{})
+	h.cancelled = make(chan struct{}, 16)
+	h.body = body
+	atomic.StoreInt32(&h.calls, 0)
+}
+
+func (h *blockingHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	release, cancelled, body := h.release, h.cancelled, h.body
+	h.mu.Unlock()
+	atomic.AddInt32(&h.calls, 1)
+	select {
+	case <-release:
+	case <-req.Context().Done():
+		cancelled <- struct{}{}
+		return
+	}
+	resp.Header().Set("Content-Type", "text/plain")
+	resp.Header().Set("X-Test-Path", req.URL.Path)
+	resp.WriteHeader(http.StatusOK)
+	resp.Write([]byte(body))
+}
+
+func TestCoalescingSuite(t *testing.T) {
+	h := &blockingHandler{}
+	s := &CoalescingSuite{
+		handler: h,
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler:           h,
+			ClientTripperware: []httpwares.Tripperware{http_coalescing.Tripperware(http_coalescing.WithMaxBodySize(64))},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type CoalescingSuite struct {
+	*httpwares_testing.WaresTestSuite
+	handler *blockingHandler
+}
+
+func (s *CoalescingSuite) SetupTest() {
+	s.handler.reset("shared response")
+}
+
+type result struct {
+	resp *http.Response
+	body string
+	err  error
+}
+
+// callAsync makes a call in the background, returning a channel with its result.
+func (s *CoalescingSuite) callAsync(ctx context.Context, method string, path string, headers ...string) <-chan result {
+	req, _ := http.NewRequest(method, "https://something.local"+path, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	client := s.NewClient()
+	out := make(chan result, 1)
+	go func() {
+		resp, err := client.Do(req.WithContext(ctx))
+		if err != nil {
+			out <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		out <- result{resp: resp, body: string(body), err: err}
+	}()
+	return out
+}
+
+// waitForCalls waits for the handler to get the number of calls, and some more time for others to pile up.
+func (s *CoalescingSuite) waitForCalls(calls int32) {
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&s.handler.calls) < calls && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+}
+
+func (s *CoalescingSuite) TestConcurrentIdenticalRequestsAreCoalesced() {
+	var results []<-chan result
+	for i := 0; i < 5; i++ {
+		results = append(results, s.callAsync(s.SimpleCtx(), "GET", "/someurl"))
+	}
+	s.waitForCalls(1)
+	close(s.handler.release)
+	for _, out := range results {
+		r := <-out
+		require.NoError(s.T(), r.err, "call shouldn't fail")
+		assert.Equal(s.T(), http.StatusOK, r.resp.StatusCode)
+		assert.Equal(s.T(), "shared response", r.body, "each caller must get the whole body")
+		assert.Equal(s.T(), "/someurl", r.resp.Header.Get("X-Test-Path"))
+	}
+	assert.EqualValues(s.T(), 1, atomic.LoadInt32(&s.handler.calls), "only one request must reach the server")
+}
+
+func (s *CoalescingSuite) TestRequestsWithDifferentKeysAreNotCoalesced() {
+	results := []<-chan result{
+		s.callAsync(s.SimpleCtx(), "GET", "/someurl", "Authorization", "Bearer a"),
+		s.callAsync(s.SimpleCtx(), "GET", "/someurl", "Authorization", "Bearer b"),
+		s.callAsync(s.SimpleCtx(), "GET", "/otherurl", "Authorization", "Bearer a"),
+		s.callAsync(s.SimpleCtx(), "POST", "/someurl", "Authorization", "Bearer a"),
+	}
+	s.waitForCalls(4)
+	close(s.handler.release)
+	for _, out := range results {
+		r := <-out
+		require.NoError(s.T(), r.err, "call shouldn't fail")
+	}
+	assert.EqualValues(s.T(), 4, atomic.LoadInt32(&s.handler.calls), "different requests must all reach the server")
+}
+
+func (s *CoalescingSuite) TestCancelledCallerDoesNotAffectOthers() {
+	ctx, cancel := context.WithCancel(s.SimpleCtx())
+	first := s.callAsync(ctx, "GET", "/someurl")
+	s.waitForCalls(1)
+	second := s.callAsync(s.SimpleCtx(), "GET", "/someurl")
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	r := <-first
+	require.Error(s.T(), r.err, "the cancelled caller must stop waiting")
+	close(s.handler.release)
+	r = <-second
+	require.NoError(s.T(), r.err, "the other caller must still get the response")
+	assert.Equal(s.T(), "shared response", r.body)
+	assert.EqualValues(s.T(), 1, atomic.LoadInt32(&s.handler.calls), "only one request must reach the server")
+}
+
+func (s *CoalescingSuite) TestRequestIsCancelledWhenAllCallersGiveUp() {
+	ctx, cancel := context.WithCancel(s.SimpleCtx())
+	first := s.callAsync(ctx, "GET", "/someurl")
+	second := s.callAsync(ctx, "GET", "/someurl")
+	s.waitForCalls(1)
+	cancel()
+	require.Error(s.T(), (<-first).err)
+	require.Error(s.T(), (<-second).err)
+	select {
+	case <-s.handler.cancelled:
+	case <-time.After(2 * time.Second):
+		s.T().Fatalf("the shared request must be cancelled once nobody waits for it")
+	}
+}
+
+func (s *CoalescingSuite) TestLargeResponsesAreFetchedByTheOtherCallers() {
+	s.handler.reset(strings.Repeat("x", 100))
+	results := []<-chan result{
+		s.callAsync(s.SimpleCtx(), "GET", "/someurl"),
+		s.callAsync(s.SimpleCtx(), "GET", "/someurl"),
+	}
+	s.waitForCalls(1)
+	close(s.handler.release)
+	for _, out := range results {
+		r := <-out
+		require.NoError(s.T(), r.err, "call shouldn't fail")
+		assert.Equal(s.T(), strings.Repeat("x", 100), r.body)
+	}
+	assert.EqualValues(s.T(), 2, atomic.LoadInt32(&s.handler.calls), "the caller that started the call must keep its response, the other one must fetch its own")
+}
+
+func (s *CoalescingSuite) TestLargeResponseIsFetchedOnceForASingleCaller() {
+	s.handler.reset(strings.Repeat("x", 100))
+	close(s.handler.release)
+	r := <-s.callAsync(s.SimpleCtx(), "GET", "/someurl")
+	require.NoError(s.T(), r.err, "call shouldn't fail")
+	assert.Equal(s.T(), strings.Repeat("x", 100), r.body, "the part of the body read to decide it was too large must be put back")
+	assert.EqualValues(s.T(), 1, atomic.LoadInt32(&s.handler.calls), "the response must not be fetched again")
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_coalescing
+
+import "net/http"
+
+var (
+	defaultOptions = &options{
+		keyHeaders:  []string{"Accept", "Accept-Encoding", "Authorization", "Cookie"},
+		maxBodySize: 1 << 20,
+	}
+)
+
+type options struct {
+	keyHeaders  []string
+	maxBodySize int64
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithKeyHeaders sets the headers whose values need to be the same for requests to be coalesced, replacing the
+// default ones.
+//
+// Any header that changes the response needs to be listed, otherwise callers could get responses meant for others.
+func WithKeyHeaders(headers ...string) Option {
+	return func(o *options) {
+		o.keyHeaders = nil
+		for _, h := range headers {
+			o.keyHeaders = append(o.keyHeaders, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// WithMaxBodySize sets the size in bytes of the largest response body that is shared. The default is 1MB.
+//
+// Responses are buffered in memory to be shared. If a response is larger, the caller that sent the request gets it as it
+// is, and each of the other callers sends its own request.
+func WithMaxBodySize(bytes int64) Option {
+	return func(o *options) {
+		o.maxBodySize = bytes
+	}
+}
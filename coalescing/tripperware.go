@@ -0,0 +1,222 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_coalescing
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// TagForCoalesced is the outbound tag set to true on requests that were served by a request sent for another caller.
+const TagForCoalesced = "http.coalesced"
+
+// Tripperware returns a new client-side ware that coalesces concurrent identical requests.
+//
+// The requests in flight are shared by all the transports wrapped with the returned Tripperware.
+func Tripperware(opts ...Option) httpwares.Tripperware {
+	o := evaluateOptions(opts)
+	g := &group{opts: o, calls: make(map[string]*call)}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if (req.Method != "GET" && req.Method != "HEAD") || req.Body != nil {
+				return next.RoundTrip(req)
+			}
+			c, joined := g.join(g.key(req), next, req)
+			if joined {
+				http_ctxtags.ExtractOutbound(req).Set(TagForCoalesced, true)
+			}
+			select {
+			case <-c.done:
+			case <-req.Context().Done():
+				g.leave(c, !joined)
+				return nil, req.Context().Err()
+			}
+			if c.tooLarge {
+				if !joined {
+					return c.handOver(req), nil
+				}
+				return next.RoundTrip(req) // the response couldn't be shared, get one of our own
+			}
+			if c.err != nil {
+				return nil, c.err
+			}
+			return c.response(req), nil
+		})
+	}
+}
+
+type group struct {
+	mu    sync.Mutex
+	opts  *options
+	calls map[string]*call
+}
+
+// call is a request in flight, shared by its waiters.
+type call struct {
+	key      string
+	done     chan struct{}
+	waiters  int
+	ctx      context.Context
+	cancel   context.CancelFunc
+	resp     *http.Response
+	body     []byte
+	err      error
+	tooLarge bool
+	// finished and leaderLeft decide who releases a response too large to share: the caller that started the call
+	// takes it over, unless it stopped waiting for it.
+	finished   bool
+	leaderLeft bool
+}
+
+func (g *group) key(req *http.Request) string {
+	parts := []string{req.Method, req.URL.String()}
+	for _, h := range g.opts.keyHeaders {
+		parts = append(parts, strings.Join(req.Header[h], ","))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// join returns the call in flight for the key, starting one if there is none, and whether it was already in flight.
+func (g *group) join(key string, next http.RoundTripper, req *http.Request) (*call, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c, ok := g.calls[key]; ok {
+		c.waiters++
+		return c, true
+	}
+	ctx, cancel := context.WithCancel(detachedContext{parent: req.Context()})
+	c := &call{key: key, done: make(chan struct{}), waiters: 1, ctx: ctx, cancel: cancel}
+	g.calls[key] = c
+	go g.run(c, next, req.WithContext(ctx))
+	return c, false
+}
+
+// leave stops waiting for the call, cancelling it if nobody else waits for it.
+func (g *group) leave(c *call, leader bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if leader {
+		c.leaderLeft = true
+		if c.finished && c.tooLarge {
+			c.release() // the response was kept for us, but we don't want it anymore
+		}
+	}
+	c.waiters--
+	if c.waiters == 0 {
+		c.cancel()
+		g.forgetLocked(c)
+	}
+}
+
+func (g *group) run(c *call, next http.RoundTripper, req *http.Request) {
+	c.resp, c.err = next.RoundTrip(req)
+	if c.err == nil {
+		c.body, c.err = readBody(c.resp.Body, g.opts.maxBodySize)
+		if c.err == errBodyTooLarge {
+			// Put back what was read, so that the caller that started the call can take the response over.
+			c.resp.Body = &handedOverBody{
+				Reader: io.MultiReader(bytes.NewReader(c.body), c.resp.Body),
+				body:   c.resp.Body,
+				cancel: c.cancel,
+			}
+			c.body = nil
+			c.err = nil
+			c.tooLarge = true
+		}
+	}
+	g.mu.Lock()
+	g.forgetLocked(c)
+	c.finished = true
+	handedOver := c.tooLarge && !c.leaderLeft
+	g.mu.Unlock()
+	if !handedOver {
+		c.release()
+	}
+	close(c.done)
+}
+
+func (g *group) forgetLocked(c *call) {
+	if g.calls[c.key] == c {
+		delete(g.calls, c.key)
+	}
+}
+
+// release closes the response kept for the caller that started the call, and cancels the call.
+func (c *call) release() {
+	if c.tooLarge {
+		c.resp.Body.Close()
+	}
+	c.cancel()
+}
+
+// handOver returns the response too large to share to the caller that started the call. The caller's cancellation
+// applies to reading the body, as it would have without coalescing.
+func (c *call) handOver(req *http.Request) *http.Response {
+	go func() {
+		select {
+		case <-req.Context().Done():
+			c.cancel()
+		case <-c.ctx.Done():
+		}
+	}()
+	resp := *c.resp
+	resp.Request = req
+	return &resp
+}
+
+// response returns a copy of the shared response for the request.
+func (c *call) response(req *http.Request) *http.Response {
+	resp := *c.resp
+	resp.Header = make(http.Header, len(c.resp.Header))
+	for k, v := range c.resp.Header {
+		resp.Header[k] = append([]string(nil), v...)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(c.body))
+	resp.Request = req
+	return &resp
+}
+
+type bodyTooLargeError struct{}
+
+func (bodyTooLargeError) Error() string {
+	return "response body too large to share"
+}
+
+var errBodyTooLarge error = bodyTooLargeError{}
+
+// readBody reads the body up to the maximum size. It closes the body, unless it is too large, in which case the part
+// that was read is returned along with errBodyTooLarge.
+func readBody(body io.ReadCloser, maxSize int64) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return data, errBodyTooLarge
+	}
+	body.Close()
+	return data, nil
+}
+
+// handedOverBody is the body of a response too large to share, with the part that was already read put back in front.
+type handedOverBody struct {
+	io.Reader
+	body   io.Closer
+	cancel context.CancelFunc
+}
+
+func (b *handedOverBody) Close() error {
+	err := b.body.Close()
+	b.cancel()
+	return err
+}
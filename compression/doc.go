@@ -0,0 +1,25 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_compression` compresses responses of handlers.
+
+The Middleware negotiates the encoding with the client using the `Accept-Encoding` header, supporting `gzip` and
+`deflate` out of the box. Other encodings, such as brotli, can be added using `WithEncoder`.
+
+Responses are not compressed if the handler already encoded them, if they are smaller than a minimum size (see
+`WithMinSize`), or if their content type is already compressed (e.g. images, see `WithExcludedContentTypes`).
+
+The `http.ResponseWriter` passed to handlers keeps the `http.Flusher`, `http.Hijacker` and `http.Pusher` interfaces of
+the original one. Flushing flushes the data compressed so far to the client.
+
+Response sizes
+
+The Middleware is built on `httpwares.WrapResponseWriterWithFilter`. Middlewares placed before this one in the chain
+see the compressed response, so the `MessageLength()` of their `httpwares.WrappedResponseWriter` is the number of
+bytes sent to the client, and `httpwares.DecodedMessageLength` returns the size of the uncompressed response. This way
+`http_metrics` reports both sizes (see `http_metrics.EncodedSizeTracker`), and `http_logrus` logs the uncompressed
+one as `http.response.uncompressed_length_bytes`. Middlewares placed after this one only see the uncompressed
+response.
+*/
+package http_compression
@@ -0,0 +1,64 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_compression
+
+import (
+	"strconv"
+	"strings"
+)
+
+// negotiateEncoding picks the encoder for the given `Accept-Encoding` header, or returns nil if the response should
+// not be encoded.
+//
+// The encoding with the highest quality value wins, ties are resolved in the order of the encoders.
+func negotiateEncoding(acceptEncoding string, encoders []encoder) *encoder {
+	if acceptEncoding == "" {
+		return nil
+	}
+	qualities := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseCoding(part)
+		if name == "" {
+			continue
+		}
+		if name == "*" {
+			wildcard = q
+		} else {
+			qualities[name] = q
+		}
+	}
+	var best *encoder
+	bestQ := 0.0
+	for i := range encoders {
+		q, ok := qualities[encoders[i].name]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = &encoders[i], q
+		}
+	}
+	return best
+}
+
+// parseCoding parses a single element of `Accept-Encoding`, e.g. "gzip;q=0.8". Malformed quality values are treated as
+// zero, so that the coding is not used.
+func parseCoding(part string) (string, float64) {
+	params := strings.Split(part, ";")
+	name := strings.ToLower(strings.TrimSpace(params[0]))
+	q := 1.0
+	for _, param := range params[1:] {
+		param = strings.TrimSpace(param)
+		if !strings.HasPrefix(param, "q=") && !strings.HasPrefix(param, "Q=") {
+			continue
+		}
+		value, err := strconv.ParseFloat(param[2:], 64)
+		if err != nil || value < 0 || value > 1 {
+			value = 0
+		}
+		q = value
+	}
+	return name, q
+}
@@ -0,0 +1,46 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_compression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	encoders := evaluateOptions([]Option{WithEncoder("br", newGzipWriter)}).encoders
+	for acceptEncoding, expected := range map[string]string{
+		"":                            "",
+		"identity":                    "",
+		"gzip":                        "gzip",
+		"GZIP":                        "gzip",
+		"deflate, gzip":               "gzip",
+		"deflate;q=1, gzip;q=0.5":     "deflate",
+		"gzip;q=0, deflate;q=0":       "",
+		"*":                           "br",
+		"*;q=0.1, gzip":               "gzip",
+		"br;q=0, *":                   "gzip",
+		"gzip;q=0.8, br;q=0.8":        "br",
+		"compress, x-unknown":         "",
+		"gzip;q=invalid, deflate;q=1": "deflate",
+	} {
+		e := negotiateEncoding(acceptEncoding, encoders)
+		name := ""
+		if e != nil {
+			name = e.name
+		}
+		assert.Equal(t, expected, name, "negotiating %q", acceptEncoding)
+	}
+}
+
+func TestWithEncoderOrdering(t *testing.T) {
+	o := evaluateOptions([]Option{WithEncoder("br", newGzipWriter), WithEncoder("zstd", newGzipWriter), WithEncoder("GZIP", newZlibWriter)})
+	var names []string
+	for _, e := range o.encoders {
+		names = append(names, e.name)
+	}
+	assert.Equal(t, []string{"br", "zstd", "gzip", "deflate"}, names, "added encoders should be preferred, in order")
+	assert.Len(t, defaultOptions.encoders, 2, "the default encoders must not be modified")
+}
@@ -0,0 +1,239 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_compression_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/compression"
+	"github.com/improbable-eng/go-httpwares/logging/logrus"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var largeContent = strings.Repeat("compress me, I'm very repetitive! ", 100)
+
+// contentHandler writes responses of different kinds, depending on the query parameters.
+func contentHandler(resp http.ResponseWriter, req *http.Request) {
+	switch req.URL.Query().Get("mode") {
+	case "small":
+		resp.Write([]byte("small"))
+	case "encoded":
+		resp.Header().Set("Content-Encoding", "custom")
+		resp.Write([]byte(largeContent))
+	case "image":
+		resp.Header().Set("Content-Type", "image/png")
+		resp.Write([]byte(largeContent))
+	case "content_length":
+		resp.Header().Set("Content-Length", strconv.Itoa(len(largeContent)))
+		resp.Write([]byte(largeContent))
+	case "streaming":
+		_, isFlusher := resp.(http.Flusher)
+		_, isHijacker := resp.(http.Hijacker)
+		if !isFlusher || !isHijacker {
+			resp.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resp.Header().Set("Content-Type", "text/plain")
+		for i := 0; i < 4; i++ {
+			resp.Write([]byte(largeContent))
+			resp.(http.Flusher).Flush()
+		}
+	case "no_content":
+		resp.WriteHeader(http.StatusNoContent)
+	default:
+		resp.Write([]byte(largeContent))
+	}
+}
+
+// lengthRecorder records the message lengths seen by middlewares placed around the compression one.
+type lengthRecorder struct {
+	mu             sync.Mutex
+	lengths        map[string]int
+	decodedLengths map[string]int
+}
+
+func (r *lengthRecorder) middleware(name string) httpwares.Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			wrapped := httpwares.WrapResponseWriter(resp)
+			next.ServeHTTP(wrapped, req)
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.lengths[name] = wrapped.MessageLength()
+			r.decodedLengths[name] = httpwares.DecodedMessageLength(wrapped)
+		})
+	}
+}
+
+func (r *lengthRecorder) length(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lengths[name]
+}
+
+func (r *lengthRecorder) decodedLength(name string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.decodedLengths[name]
+}
+
+func TestCompressionSuite(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	log := logrus.New()
+	log.Out = httpwares_testing.NewMutexReadWriter(buffer)
+	log.Formatter = &logrus.JSONFormatter{DisableTimestamp: true}
+	recorder := &lengthRecorder{lengths: make(map[string]int), decodedLengths: make(map[string]int)}
+	s := &CompressionSuite{
+		buffer:   buffer,
+		recorder: recorder,
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: http.HandlerFunc(contentHandler),
+			ServerMiddleware: []httpwares.Middleware{
+				http_logrus.Middleware(logrus.NewEntry(log)),
+				recorder.middleware("outer"),
+				http_compression.Middleware(http_compression.WithMinSize(100)),
+				recorder.middleware("inner"),
+			},
+			ClientInLegacyHttp1Mode: true, // so that hijacking is available
+		},
+	}
+	suite.Run(t, s)
+}
+
+type CompressionSuite struct {
+	*httpwares_testing.WaresTestSuite
+	buffer   *bytes.Buffer
+	recorder *lengthRecorder
+}
+
+func (s *CompressionSuite) SetupTest() {
+	s.buffer.Reset()
+}
+
+func (s *CompressionSuite) call(method string, mode string, acceptEncoding string) (*http.Response, []byte) {
+	req, _ := http.NewRequest(method, "https://something.local/someurl?mode="+mode, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding) // disables the transparent decompression of the client
+	}
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	var body io.Reader = resp.Body
+	switch resp.Header.Get("Content-Encoding") {
+	case "gzip":
+		body, err = gzip.NewReader(resp.Body)
+		require.NoError(s.T(), err, "response should be gzipped")
+	case "deflate":
+		body, err = zlib.NewReader(resp.Body)
+		require.NoError(s.T(), err, "response should be deflated")
+	}
+	content, err := ioutil.ReadAll(body)
+	require.NoError(s.T(), err, "reading the body shouldn't fail")
+	return resp, content
+}
+
+func (s *CompressionSuite) TestCompressesWithGzip() {
+	resp, content := s.call("GET", "large", "gzip, deflate")
+	assert.Equal(s.T(), "gzip", resp.Header.Get("Content-Encoding"), "gzip should be preferred")
+	assert.Equal(s.T(), "Accept-Encoding", resp.Header.Get("Vary"))
+	assert.Equal(s.T(), "text/plain; charset=utf-8", resp.Header.Get("Content-Type"), "content type should be detected from the uncompressed content")
+	assert.Equal(s.T(), largeContent, string(content))
+}
+
+func (s *CompressionSuite) TestCompressesWithDeflate() {
+	resp, content := s.call("GET", "large", "gzip;q=0.5, deflate")
+	assert.Equal(s.T(), "deflate", resp.Header.Get("Content-Encoding"), "the preferred encoding of the client should be used")
+	assert.Equal(s.T(), largeContent, string(content))
+}
+
+func (s *CompressionSuite) TestMessageLengthsAreReported() {
+	s.call("GET", "large", "gzip")
+	compressed, uncompressed := s.recorder.length("outer"), s.recorder.length("inner")
+	assert.Equal(s.T(), len(largeContent), uncompressed, "middlewares after compression should see the uncompressed size")
+	assert.True(s.T(), compressed > 0 && compressed < uncompressed/10, "middlewares before compression should see the compressed size, got %d", compressed)
+	assert.Equal(s.T(), len(largeContent), s.recorder.decodedLength("outer"), "middlewares before compression should see the uncompressed size as the decoded one")
+	assert.Contains(s.T(), s.buffer.String(), `"http.response.uncompressed_length_bytes":`+strconv.Itoa(len(largeContent)), "the uncompressed size should be logged")
+	assert.Contains(s.T(), s.buffer.String(), `"http.response.length_bytes":`+strconv.Itoa(compressed), "the compressed size should be logged")
+}
+
+func (s *CompressionSuite) TestDoesNotCompressWithoutAcceptEncoding() {
+	resp, content := s.call("GET", "large", "identity")
+	assert.Empty(s.T(), resp.Header.Get("Content-Encoding"))
+	assert.Equal(s.T(), "Accept-Encoding", resp.Header.Get("Vary"), "caches need to know the response depends on the encoding")
+	assert.Equal(s.T(), largeContent, string(content))
+	assert.Equal(s.T(), s.recorder.length("outer"), s.recorder.decodedLength("outer"), "uncompressed responses should have the same decoded size")
+	assert.NotContains(s.T(), s.buffer.String(), "http.response.uncompressed_length_bytes", "the uncompressed size should only be logged for compressed responses")
+}
+
+func (s *CompressionSuite) TestSkipsSmallResponses() {
+	resp, content := s.call("GET", "small", "gzip")
+	assert.Empty(s.T(), resp.Header.Get("Content-Encoding"), "small responses should not be compressed")
+	assert.Equal(s.T(), "small", string(content))
+}
+
+func (s *CompressionSuite) TestSkipsEncodedResponses() {
+	resp, content := s.call("GET", "encoded", "gzip")
+	assert.Equal(s.T(), "custom", resp.Header.Get("Content-Encoding"), "encoded responses should not be compressed again")
+	assert.Equal(s.T(), largeContent, string(content))
+}
+
+func (s *CompressionSuite) TestSkipsExcludedContentTypes() {
+	resp, content := s.call("GET", "image", "gzip")
+	assert.Empty(s.T(), resp.Header.Get("Content-Encoding"), "images should not be compressed")
+	assert.Equal(s.T(), largeContent, string(content))
+}
+
+func (s *CompressionSuite) TestDropsContentLengthWhenCompressing() {
+	resp, content := s.call("GET", "content_length", "gzip")
+	assert.Equal(s.T(), "gzip", resp.Header.Get("Content-Encoding"))
+	assert.NotEqual(s.T(), int64(len(largeContent)), resp.ContentLength, "the uncompressed length must not be sent")
+	assert.Equal(s.T(), largeContent, string(content))
+}
+
+func (s *CompressionSuite) TestStreamingResponsesAreFlushed() {
+	resp, content := s.call("GET", "streaming", "gzip")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode, "flushing and hijacking should be available")
+	assert.Equal(s.T(), "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(s.T(), strings.Repeat(largeContent, 4), string(content))
+}
+
+func (s *CompressionSuite) TestResponsesWithoutBodyAreNotCompressed() {
+	resp, _ := s.call("GET", "no_content", "gzip")
+	assert.Equal(s.T(), http.StatusNoContent, resp.StatusCode)
+	assert.Empty(s.T(), resp.Header.Get("Content-Encoding"))
+	resp, _ = s.call("HEAD", "large", "gzip")
+	assert.Empty(s.T(), resp.Header.Get("Content-Encoding"), "HEAD responses should not be compressed")
+}
+
+func TestCustomEncoder(t *testing.T) {
+	customGzip := func(w io.Writer, level int) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, level)
+	}
+	handler := http_compression.Middleware(http_compression.WithEncoder("x-custom", customGzip))(http.HandlerFunc(contentHandler))
+	req := httptest.NewRequest("GET", "/someurl", nil)
+	req.Header.Set("Accept-Encoding", "gzip, x-custom")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, "x-custom", recorder.Header().Get("Content-Encoding"), "custom encoders should be preferred")
+	reader, err := gzip.NewReader(recorder.Body)
+	require.NoError(t, err)
+	content, err := ioutil.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, largeContent, string(content))
+}
@@ -0,0 +1,49 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_compression
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/logging/logrus/ctxlogrus"
+	"github.com/sirupsen/logrus"
+)
+
+// Middleware returns a http.Handler middleware that compresses responses.
+//
+// Responses are compressed with the encoding preferred by the client out of the supported ones. If the client doesn't
+// accept any of them, the response is sent as it is.
+func Middleware(opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			addVary(resp.Header(), "Accept-Encoding")
+			e := negotiateEncoding(req.Header.Get("Accept-Encoding"), o.encoders)
+			if e == nil {
+				next.ServeHTTP(resp, req)
+				return
+			}
+			wrapped := httpwares.WrapResponseWriter(resp)
+			filter := newCompressFilter(wrapped, req, o, e)
+			next.ServeHTTP(httpwares.WrapResponseWriterWithFilter(wrapped, filter), req)
+			// Errors here come from writing to the client, which the handler would have seen already.
+			if compressed, _ := filter.close(); compressed {
+				ctxlogrus.AddFields(req.Context(), logrus.Fields{"http.response.encoding": e.name})
+			}
+		})
+	}
+}
+
+func addVary(h http.Header, header string) {
+	for _, v := range h["Vary"] {
+		for _, existing := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(existing), header) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", header)
+}
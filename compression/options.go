@@ -0,0 +1,131 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_compression
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+)
+
+var (
+	defaultOptions = &options{
+		encoders: []encoder{
+			{name: "gzip", new: newGzipWriter},
+			{name: "deflate", new: newZlibWriter},
+		},
+		level:   DefaultCompression,
+		minSize: 1024,
+		excludedContentTypes: []string{
+			"image/",
+			"video/",
+			"audio/",
+			"font/woff",
+			"application/zip",
+			"application/gzip",
+			"application/x-gzip",
+			"application/octet-stream",
+		},
+	}
+)
+
+const (
+	// DefaultCompression is the default compression level of encoders.
+	DefaultCompression = -1
+	// BestSpeed is the fastest compression level of the built in encoders.
+	BestSpeed = 1
+	// BestCompression is the smallest compression level of the built in encoders.
+	BestCompression = 9
+)
+
+type options struct {
+	encoders             []encoder
+	level                int
+	minSize              int
+	excludedContentTypes []string
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.encoders = append([]encoder(nil), defaultOptions.encoders...)
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// EncoderFunc creates a writer that compresses the data written to it into w, using the given compression level.
+//
+// If the returned writer has a `Flush() error` method, it is called when the handler flushes the response.
+type EncoderFunc func(w io.Writer, level int) (io.WriteCloser, error)
+
+type encoder struct {
+	name   string
+	new    EncoderFunc
+	custom bool
+}
+
+// WithEncoder adds an encoding (e.g. "br" for brotli) to the ones supported, or replaces the built in one of the
+// same name.
+//
+// Encodings added with this option are preferred to the built in ones when the client accepts them equally, in the
+// order they were added.
+func WithEncoder(name string, f EncoderFunc) Option {
+	return func(o *options) {
+		name = strings.ToLower(name)
+		encoders := []encoder{}
+		for _, e := range o.encoders {
+			if e.name != name {
+				encoders = append(encoders, e)
+			}
+		}
+		added := 0
+		for added < len(encoders) && encoders[added].custom {
+			added++
+		}
+		o.encoders = append(encoders[:added], append([]encoder{{name: name, new: f, custom: true}}, encoders[added:]...)...)
+	}
+}
+
+// WithLevel sets the compression level passed to encoders. The default is `DefaultCompression`.
+func WithLevel(level int) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithMinSize sets the size in bytes below which responses are not compressed. The default is 1024 bytes.
+//
+// Responses without a `Content-Length` header are buffered up to this size before deciding.
+func WithMinSize(bytes int) Option {
+	return func(o *options) {
+		o.minSize = bytes
+	}
+}
+
+// WithExcludedContentTypes sets the content types of responses that are not compressed, replacing the default ones.
+//
+// Content types are matched by their prefix, so "image/" excludes all images. By default images, audio, video, fonts
+// and archives are excluded, as they are already compressed.
+func WithExcludedContentTypes(contentTypes ...string) Option {
+	return func(o *options) {
+		o.excludedContentTypes = nil
+		for _, c := range contentTypes {
+			o.excludedContentTypes = append(o.excludedContentTypes, strings.ToLower(c))
+		}
+	}
+}
+
+func newGzipWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, level)
+}
+
+// newZlibWriter encodes "deflate", which despite its name is the zlib format (RFC 1950) in HTTP.
+func newZlibWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	return zlib.NewWriterLevel(w, level)
+}
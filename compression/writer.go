@@ -0,0 +1,159 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_compression
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/improbable-eng/go-httpwares"
+)
+
+// compressFilter compresses the response written by the handler, if it turns out to be worth it.
+//
+// The decision is made once the headers are final and either the `Content-Length` is known, the minimum size has been
+// buffered, or the handler flushes or returns.
+type compressFilter struct {
+	resp        httpwares.WrappedResponseWriter
+	req         *http.Request
+	opts        *options
+	encoder     *encoder
+	code        int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         io.WriteCloser
+}
+
+func newCompressFilter(resp httpwares.WrappedResponseWriter, req *http.Request, opts *options, e *encoder) *compressFilter {
+	return &compressFilter{resp: resp, req: req, opts: opts, encoder: e}
+}
+
+func (w *compressFilter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.code = code
+	h := w.resp.Header()
+	if !bodyAllowed(w.req, code) || h.Get("Content-Encoding") != "" || h.Get("Content-Length") != "" {
+		w.decide() // nothing to wait for
+	}
+}
+
+func (w *compressFilter) Write(buf []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, buf...)
+		if len(w.buf) < w.opts.minSize {
+			return len(buf), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(buf), nil
+	}
+	if w.enc != nil {
+		return w.enc.Write(buf)
+	}
+	return w.resp.Write(buf)
+}
+
+func (w *compressFilter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.decided {
+		w.decide()
+	}
+	if flusher, ok := w.enc.(interface {
+		Flush() error
+	}); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := w.resp.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Encoded implements `httpwares.EncodingResponseFilter`.
+func (w *compressFilter) Encoded() bool {
+	return w.enc != nil
+}
+
+// close finishes the response once the handler returned, and reports whether it was compressed.
+func (w *compressFilter) close() (bool, error) {
+	if !w.wroteHeader {
+		return false, nil
+	}
+	if !w.decided {
+		if err := w.decide(); err != nil {
+			return false, err
+		}
+	}
+	if w.enc == nil {
+		return false, nil
+	}
+	return true, w.enc.Close()
+}
+
+// decide sends the headers, compressed or not, and the response buffered so far.
+func (w *compressFilter) decide() error {
+	w.decided = true
+	buf := w.buf
+	w.buf = nil
+	if w.shouldCompress(buf) {
+		enc, err := w.encoder.new(w.resp, w.opts.level)
+		if err == nil {
+			h := w.resp.Header()
+			if h.Get("Content-Type") == "" && len(buf) > 0 {
+				h.Set("Content-Type", http.DetectContentType(buf)) // otherwise the compressed data would be sniffed
+			}
+			h.Del("Content-Length")
+			h.Set("Content-Encoding", w.encoder.name)
+			w.enc = enc
+		}
+	}
+	w.resp.WriteHeader(w.code)
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.resp.Write(buf)
+	}
+	return err
+}
+
+func (w *compressFilter) shouldCompress(buf []byte) bool {
+	h := w.resp.Header()
+	if !bodyAllowed(w.req, w.code) || w.code == http.StatusPartialContent {
+		return false
+	}
+	if h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := h.Get("Content-Type")
+	if contentType == "" && len(buf) > 0 {
+		contentType = http.DetectContentType(buf)
+	}
+	contentType = strings.ToLower(contentType)
+	for _, excluded := range w.opts.excludedContentTypes {
+		if strings.HasPrefix(contentType, excluded) {
+			return false
+		}
+	}
+	size := len(buf)
+	if length, err := strconv.Atoi(h.Get("Content-Length")); err == nil {
+		size = length
+	}
+	return size > 0 && size >= w.opts.minSize
+}
+
+func bodyAllowed(req *http.Request, code int) bool {
+	if req.Method == "HEAD" {
+		return false
+	}
+	return code >= 200 && code != http.StatusNoContent && code != http.StatusNotModified
+}
@@ -0,0 +1,116 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_concurrency
+
+import (
+	"math"
+	"time"
+)
+
+// Sample describes a request that was handled.
+type Sample struct {
+	// Latency is how long the handler took, not counting the time spent in the queue.
+	Latency time.Duration
+	// InFlight is the number of requests handled at the time the request started, including itself.
+	InFlight int
+	// Failed is true if the handler responded with a 5xx status code.
+	Failed bool
+}
+
+// LimitAlgorithm decides the limit of concurrent requests.
+//
+// Calls to a LimitAlgorithm are serialised by the Middleware, so implementations don't need to be thread safe. Each
+// handler group uses a separate LimitAlgorithm.
+type LimitAlgorithm interface {
+	// Limit returns the current limit of concurrent requests.
+	Limit() int
+	// Observe updates the limit with the result of a handled request.
+	Observe(sample Sample)
+}
+
+// NewFixedLimit returns a LimitAlgorithm that always allows `limit` concurrent requests.
+func NewFixedLimit(limit int) LimitAlgorithm {
+	return fixedLimit(limit)
+}
+
+type fixedLimit int
+
+func (l fixedLimit) Limit() int {
+	return int(l)
+}
+
+func (l fixedLimit) Observe(sample Sample) {}
+
+// NewAIMDLimit returns a LimitAlgorithm that uses additive increase and multiplicative decrease.
+//
+// The limit starts at `initial` and grows by one with each request handled within `latencyThreshold` while the
+// server is busy (i.e. at least half of the limit is in use). Each request that fails or takes longer than
+// `latencyThreshold` cuts the limit by 10%. The limit always stays between `min` and `max`.
+func NewAIMDLimit(initial int, min int, max int, latencyThreshold time.Duration) LimitAlgorithm {
+	return &aimdLimit{limit: float64(initial), min: float64(min), max: float64(max), threshold: latencyThreshold}
+}
+
+type aimdLimit struct {
+	limit     float64
+	min       float64
+	max       float64
+	threshold time.Duration
+}
+
+func (l *aimdLimit) Limit() int {
+	return int(l.limit)
+}
+
+func (l *aimdLimit) Observe(sample Sample) {
+	if sample.Failed || sample.Latency > l.threshold {
+		l.limit = l.limit * 0.9
+	} else if float64(sample.InFlight)*2 >= l.limit {
+		l.limit = l.limit + 1
+	}
+	l.limit = clamp(l.limit, l.min, l.max)
+}
+
+// NewGradientLimit returns a LimitAlgorithm that follows the gradient of the latency of requests.
+//
+// The lowest latency seen is taken to be the latency of the server when it is not overloaded. The ratio between it and
+// the latency of each request (the gradient) shows how much the requests are queueing up inside the server, and the
+// limit is scaled by it, with some headroom for growth. The lowest latency is reset every `probeInterval` requests, so
+// that changes of the server's baseline are picked up. The limit starts at `initial` and stays between `min` and
+// `max`.
+func NewGradientLimit(initial int, min int, max int, probeInterval int) LimitAlgorithm {
+	return &gradientLimit{limit: float64(initial), min: float64(min), max: float64(max), probeInterval: probeInterval}
+}
+
+type gradientLimit struct {
+	limit         float64
+	min           float64
+	max           float64
+	probeInterval int
+	samples       int
+	minLatency    time.Duration
+}
+
+func (l *gradientLimit) Limit() int {
+	return int(l.limit)
+}
+
+func (l *gradientLimit) Observe(sample Sample) {
+	l.samples++
+	if l.minLatency == 0 || sample.Latency < l.minLatency || (l.probeInterval > 0 && l.samples%l.probeInterval == 0) {
+		l.minLatency = sample.Latency
+	}
+	gradient := 0.5
+	if !sample.Failed && sample.Latency > 0 {
+		gradient = clamp(float64(l.minLatency)/float64(sample.Latency), 0.5, 1.0)
+	}
+	newLimit := l.limit*gradient + math.Sqrt(l.limit)
+	if float64(sample.InFlight)*2 < l.limit && newLimit > l.limit {
+		newLimit = l.limit // don't grow the limit if it isn't used
+	}
+	l.limit = clamp(0.8*l.limit+0.2*newLimit, l.min, l.max)
+}
+
+func clamp(value float64, min float64, max float64) float64 {
+	return math.Max(min, math.Min(max, value))
+}
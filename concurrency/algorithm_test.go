@@ -0,0 +1,49 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_concurrency_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares/concurrency"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIMDLimit(t *testing.T) {
+	l := http_concurrency.NewAIMDLimit(10, 5, 12, 100*time.Millisecond)
+	l.Observe(http_concurrency.Sample{Latency: 10 * time.Millisecond, InFlight: 2})
+	assert.Equal(t, 10, l.Limit(), "limit shouldn't grow when it isn't used")
+	l.Observe(http_concurrency.Sample{Latency: 10 * time.Millisecond, InFlight: 5})
+	assert.Equal(t, 11, l.Limit(), "limit should grow additively")
+	l.Observe(http_concurrency.Sample{Latency: 10 * time.Millisecond, InFlight: 10})
+	l.Observe(http_concurrency.Sample{Latency: 10 * time.Millisecond, InFlight: 10})
+	assert.Equal(t, 12, l.Limit(), "limit shouldn't grow over the max")
+	l.Observe(http_concurrency.Sample{Latency: 200 * time.Millisecond, InFlight: 10})
+	assert.Equal(t, 10, l.Limit(), "slow requests should cut the limit")
+	l.Observe(http_concurrency.Sample{Latency: 10 * time.Millisecond, InFlight: 10, Failed: true})
+	assert.Equal(t, 9, l.Limit(), "failed requests should cut the limit")
+	for i := 0; i < 20; i++ {
+		l.Observe(http_concurrency.Sample{Latency: 200 * time.Millisecond, InFlight: 10})
+	}
+	assert.Equal(t, 5, l.Limit(), "limit shouldn't drop below the min")
+}
+
+func TestGradientLimit(t *testing.T) {
+	l := http_concurrency.NewGradientLimit(20, 1, 100, 0)
+	for i := 0; i < 50; i++ {
+		l.Observe(http_concurrency.Sample{Latency: 10 * time.Millisecond, InFlight: l.Limit()})
+	}
+	grown := l.Limit()
+	assert.True(t, grown > 20, "limit should grow while the latency is steady, got %d", grown)
+	for i := 0; i < 50; i++ {
+		l.Observe(http_concurrency.Sample{Latency: 40 * time.Millisecond, InFlight: l.Limit()})
+	}
+	assert.True(t, l.Limit() < grown, "limit should shrink when the latency grows, got %d", l.Limit())
+	limit := l.Limit()
+	for i := 0; i < 50; i++ {
+		l.Observe(http_concurrency.Sample{Latency: 10 * time.Millisecond, InFlight: 1})
+	}
+	assert.Equal(t, limit, l.Limit(), "limit shouldn't grow when it isn't used")
+}
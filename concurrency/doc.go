@@ -0,0 +1,24 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_concurrency` is a HTTP server-side Middleware that limits the number of requests handled at the same time.
+
+Load Shedding
+
+Each handler group (as set by `http_ctxtags.Middleware`) gets its own limit of concurrent requests. Requests over the
+limit can wait in a bounded queue for a while, and are shed with a 503 (Service Unavailable) status code if the queue
+is full or they wait for too long. Shedding excess load early keeps the latency of the requests that are handled low,
+instead of making all of them slow.
+
+Adaptive Limits
+
+The limit is set by a `LimitAlgorithm`. Apart from a fixed limit, the AIMD and gradient algorithms adjust the limit
+based on the observed latency of requests, so that it doesn't need to be hand-tuned for each service.
+
+Monitoring
+
+If the `http_metrics.Reporter` passed with `WithReporter` implements `http_metrics.LimitReporter` (as the one of
+`http_prometheus` does), the current limit and queue depth of each handler group are reported.
+*/
+package http_concurrency
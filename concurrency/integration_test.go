@@ -0,0 +1,150 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_concurrency_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/concurrency"
+	"github.com/improbable-eng/go-httpwares/metrics"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	queueTimeout = 200 * time.Millisecond
+)
+
+// blockingHandler blocks requests until they are unblocked by the test.
+type blockingHandler struct {
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (h *blockingHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	h.started <- struct{}{}
+	<-h.unblock
+	httpwares_testing.PingBackHandler(httpwares_testing.DefaultPingBackStatusCode).ServeHTTP(resp, req)
+}
+
+// limitReporter records the reported limits and queue depths.
+type limitReporter struct {
+	mu     sync.Mutex
+	limits map[string]int
+	depths []int
+}
+
+func (r *limitReporter) Track(req *http.Request) http_metrics.Tracker {
+	return nil // not used by the concurrency middleware
+}
+
+func (r *limitReporter) LimitChanged(handlerGroup string, limit int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limits[handlerGroup] = limit
+}
+
+func (r *limitReporter) QueueDepthChanged(handlerGroup string, depth int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.depths = append(r.depths, depth)
+}
+
+func TestConcurrencySuite(t *testing.T) {
+	h := &blockingHandler{started: make(chan struct{}, 10), unblock: make(chan struct{})}
+	reporter := &limitReporter{limits: make(map[string]int)}
+	s := &ConcurrencySuite{
+		h:        h,
+		reporter: reporter,
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: h,
+			ServerMiddleware: []httpwares.Middleware{
+				http_ctxtags.Middleware("limited"),
+				http_concurrency.Middleware(
+					http_concurrency.WithGroupLimitAlgorithm("limited", func() http_concurrency.LimitAlgorithm {
+						return http_concurrency.NewFixedLimit(2)
+					}),
+					http_concurrency.WithQueue(1, queueTimeout),
+					http_concurrency.WithReporter(reporter),
+				),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type ConcurrencySuite struct {
+	*httpwares_testing.WaresTestSuite
+	h        *blockingHandler
+	reporter *limitReporter
+}
+
+func (s *ConcurrencySuite) call(results chan<- int) {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	if !assert.NoError(s.T(), err, "call shouldn't fail") {
+		results <- 0
+		return
+	}
+	resp.Body.Close()
+	results <- resp.StatusCode
+}
+
+func (s *ConcurrencySuite) waitForHandler() {
+	select {
+	case <-s.h.started:
+	case <-time.After(time.Second):
+		s.T().Fatalf("request didn't reach the handler")
+	}
+}
+
+func (s *ConcurrencySuite) TestQueuesAndShedsOverLimit() {
+	results := make(chan int, 4)
+	go s.call(results)
+	go s.call(results)
+	s.waitForHandler()
+	s.waitForHandler()
+	go s.call(results) // queued
+	time.Sleep(queueTimeout / 4)
+	go s.call(results) // shed, as the queue is full
+	assert.Equal(s.T(), http.StatusServiceUnavailable, <-results, "request over the queue size should be shed")
+
+	s.h.unblock <- struct{}{} // let one of the first requests finish, admitting the queued one
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, <-results, "the unblocked request should succeed")
+	s.waitForHandler()
+	close(s.h.unblock)
+	for i := 0; i < 2; i++ {
+		assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, <-results, "the remaining requests should succeed")
+	}
+	s.h.unblock = make(chan struct{})
+
+	s.reporter.mu.Lock()
+	defer s.reporter.mu.Unlock()
+	assert.Equal(s.T(), map[string]int{"limited": 2}, s.reporter.limits, "the limit should be reported")
+	assert.Equal(s.T(), []int{1, 0}, s.reporter.depths, "the queue depth should be reported")
+}
+
+func (s *ConcurrencySuite) TestShedsAfterQueueTimeout() {
+	results := make(chan int, 3)
+	go s.call(results)
+	go s.call(results)
+	s.waitForHandler()
+	s.waitForHandler()
+	start := time.Now()
+	go s.call(results)
+	require.Equal(s.T(), http.StatusServiceUnavailable, <-results, "queued request should be shed after the timeout")
+	assert.True(s.T(), time.Since(start) >= queueTimeout, "request should have waited in the queue")
+	close(s.h.unblock)
+	for i := 0; i < 2; i++ {
+		assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, <-results, "the blocked requests should succeed")
+	}
+	s.h.unblock = make(chan struct{})
+}
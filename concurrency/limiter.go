@@ -0,0 +1,127 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_concurrency
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares/metrics"
+)
+
+// limiter admits requests of a single handler group.
+type limiter struct {
+	mu           sync.Mutex
+	group        string
+	algorithm    LimitAlgorithm
+	inFlight     int
+	queue        *list.List // of chan struct{}, closed when the request is admitted
+	queueSize    int
+	queueTimeout time.Duration
+	reporter     http_metrics.LimitReporter
+}
+
+func newLimiter(group string, algorithm LimitAlgorithm, opts *options) *limiter {
+	l := &limiter{
+		group:        group,
+		algorithm:    algorithm,
+		queue:        list.New(),
+		queueSize:    opts.queueSize,
+		queueTimeout: opts.queueTimeout,
+	}
+	l.reporter, _ = opts.reporter.(http_metrics.LimitReporter)
+	if l.reporter != nil {
+		l.reporter.LimitChanged(group, l.limit())
+	}
+	return l
+}
+
+// acquire admits a request, waiting in the queue if needed. It returns the number of requests in flight after
+// admitting it, or false if the request should be shed.
+func (l *limiter) acquire(ctx context.Context) (int, bool) {
+	l.mu.Lock()
+	if l.inFlight < l.limit() {
+		l.inFlight++
+		inFlight := l.inFlight
+		l.mu.Unlock()
+		return inFlight, true
+	}
+	if l.queue.Len() >= l.queueSize {
+		l.mu.Unlock()
+		return 0, false
+	}
+	admitted := make(chan struct{})
+	element := l.queue.PushBack(admitted)
+	l.reportQueueDepth()
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case <-admitted:
+		return l.currentInFlight(), true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	select {
+	case <-admitted:
+		// Admitted while giving up, give the slot back to the next request.
+		l.inFlight--
+		l.admitQueued()
+	default:
+		l.queue.Remove(element)
+		l.reportQueueDepth()
+	}
+	return 0, false
+}
+
+// release marks a request as done.
+func (l *limiter) release(sample Sample) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	before := l.limit()
+	l.algorithm.Observe(sample)
+	if after := l.limit(); after != before && l.reporter != nil {
+		l.reporter.LimitChanged(l.group, after)
+	}
+	l.admitQueued()
+}
+
+func (l *limiter) admitQueued() {
+	admittedAny := false
+	for l.inFlight < l.limit() && l.queue.Len() > 0 {
+		admitted := l.queue.Remove(l.queue.Front()).(chan struct{})
+		l.inFlight++
+		close(admitted)
+		admittedAny = true
+	}
+	if admittedAny {
+		l.reportQueueDepth()
+	}
+}
+
+func (l *limiter) currentInFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// limit returns the limit of the algorithm, making sure at least one request can always be handled.
+func (l *limiter) limit() int {
+	if limit := l.algorithm.Limit(); limit > 1 {
+		return limit
+	}
+	return 1
+}
+
+func (l *limiter) reportQueueDepth() {
+	if l.reporter != nil {
+		l.reporter.QueueDepthChanged(l.group, l.queue.Len())
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_concurrency
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// Middleware returns a http.Handler middleware that limits the number of requests handled concurrently.
+//
+// Limits are kept per handler group, so `http_ctxtags.Middleware` needs to be placed before this one in the chain.
+// Requests without a handler group share a single limit. Requests that can't be handled are shed with a 503
+// (Service Unavailable) status code.
+func Middleware(opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	limiters := &limiterSet{opts: o, limiters: make(map[string]*limiter)}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			group, _ := http_ctxtags.ExtractInbound(req).Values()[http_ctxtags.TagForHandlerGroup].(string)
+			l := limiters.get(group)
+			inFlight, ok := l.acquire(req.Context())
+			if !ok {
+				http.Error(resp, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				return
+			}
+			wrapped := httpwares.WrapResponseWriter(resp)
+			start := time.Now()
+			defer func() {
+				l.release(Sample{Latency: time.Since(start), InFlight: inFlight, Failed: wrapped.StatusCode() >= 500})
+			}()
+			next.ServeHTTP(wrapped, req)
+		})
+	}
+}
+
+type limiterSet struct {
+	mu       sync.Mutex
+	opts     *options
+	limiters map[string]*limiter
+}
+
+func (s *limiterSet) get(group string) *limiter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.limiters[group]
+	if !ok {
+		newAlgorithm, ok := s.opts.groupAlgorithms[group]
+		if !ok {
+			newAlgorithm = s.opts.newAlgorithm
+		}
+		l = newLimiter(group, newAlgorithm(), s.opts)
+		s.limiters[group] = l
+	}
+	return l
+}
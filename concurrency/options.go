@@ -0,0 +1,79 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_concurrency
+
+import (
+	"time"
+
+	"github.com/improbable-eng/go-httpwares/metrics"
+)
+
+var (
+	defaultOptions = &options{
+		newAlgorithm: func() LimitAlgorithm {
+			return NewFixedLimit(100)
+		},
+		groupAlgorithms: map[string]func() LimitAlgorithm{},
+		queueSize:       0,
+		queueTimeout:    0,
+		reporter:        nil,
+	}
+)
+
+type options struct {
+	newAlgorithm    func() LimitAlgorithm
+	groupAlgorithms map[string]func() LimitAlgorithm
+	queueSize       int
+	queueTimeout    time.Duration
+	reporter        http_metrics.Reporter
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.groupAlgorithms = make(map[string]func() LimitAlgorithm)
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithLimitAlgorithm sets the function creating the LimitAlgorithm for each handler group.
+//
+// By default each handler group has a fixed limit of 100 concurrent requests.
+func WithLimitAlgorithm(newAlgorithm func() LimitAlgorithm) Option {
+	return func(o *options) {
+		o.newAlgorithm = newAlgorithm
+	}
+}
+
+// WithGroupLimitAlgorithm sets the function creating the LimitAlgorithm of the given handler group, overriding
+// `WithLimitAlgorithm`.
+//
+// Each Middleware created with the option gets its own LimitAlgorithm, so that their limits don't affect each other.
+func WithGroupLimitAlgorithm(handlerGroup string, newAlgorithm func() LimitAlgorithm) Option {
+	return func(o *options) {
+		o.groupAlgorithms[handlerGroup] = newAlgorithm
+	}
+}
+
+// WithQueue lets up to `size` requests over the limit wait for up to `timeout` before being shed.
+//
+// By default requests over the limit are shed straight away.
+func WithQueue(size int, timeout time.Duration) Option {
+	return func(o *options) {
+		o.queueSize = size
+		o.queueTimeout = timeout
+	}
+}
+
+// WithReporter sets the Reporter that the limits and queue depths are reported to, if it implements
+// `http_metrics.LimitReporter`.
+func WithReporter(reporter http_metrics.Reporter) Option {
+	return func(o *options) {
+		o.reporter = reporter
+	}
+}
@@ -0,0 +1,14 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_deadline` propagates deadlines of requests between services, the way gRPC does.
+
+The Tripperware writes the time remaining until the deadline of the request's `context.Context`, minus a safety
+margin, in the `X-Request-Timeout` header of outbound requests. The Middleware reads the header of inbound requests and
+applies it as the deadline of the request's context, which is then propagated further by the Tripperware.
+
+The timeout is encoded the same way as the `Grpc-Timeout` header: an integer of up to 8 digits followed by a unit, one
+of `H` (hours), `M` (minutes), `S` (seconds), `m` (milliseconds), `u` (microseconds) or `n` (nanoseconds), e.g. `250m`.
+*/
+package http_deadline
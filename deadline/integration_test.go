@@ -0,0 +1,115 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_deadline_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/deadline"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	receivedTimeoutHeader = "x-test-received-timeout"
+	remainingMillisHeader = "x-test-remaining-millis"
+)
+
+// deadlineHandler reports back the timeout header it received and the time left until the deadline of its context.
+func deadlineHandler(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set(receivedTimeoutHeader, req.Header.Get("X-Request-Timeout"))
+	if deadline, ok := req.Context().Deadline(); ok {
+		resp.Header().Set(remainingMillisHeader, strconv.FormatInt(int64(deadline.Sub(time.Now())/time.Millisecond), 10))
+	}
+	resp.WriteHeader(http.StatusOK)
+}
+
+func TestDeadlineSuite(t *testing.T) {
+	s := &DeadlineSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: http.HandlerFunc(deadlineHandler),
+			ServerMiddleware: []httpwares.Middleware{
+				http_deadline.Middleware(http_deadline.WithMaxTimeout(10 * time.Second)),
+			},
+			ClientTripperware: []httpwares.Tripperware{
+				http_deadline.Tripperware(http_deadline.WithSafetyMargin(100 * time.Millisecond)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type DeadlineSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *DeadlineSuite) call(ctx context.Context, timeoutHeader string) (*http.Response, error) {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	if timeoutHeader != "" {
+		req.Header.Set("X-Request-Timeout", timeoutHeader)
+	}
+	return s.NewClient().Do(req.WithContext(ctx))
+}
+
+func (s *DeadlineSuite) remainingMillis(resp *http.Response) int64 {
+	remaining, err := strconv.ParseInt(resp.Header.Get(remainingMillisHeader), 10, 64)
+	require.NoError(s.T(), err, "the handler should have a deadline")
+	return remaining
+}
+
+func (s *DeadlineSuite) TestDeadlineIsPropagated() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := s.call(ctx, "")
+	require.NoError(s.T(), err, "call shouldn't fail")
+	assert.NotEmpty(s.T(), resp.Header.Get(receivedTimeoutHeader), "the timeout should be sent")
+	remaining := s.remainingMillis(resp)
+	assert.True(s.T(), remaining <= 1900, "the safety margin should be taken off the deadline, got %dms", remaining)
+	assert.True(s.T(), remaining > 1000, "the handler should get most of the time left, got %dms", remaining)
+}
+
+func (s *DeadlineSuite) TestNoDeadlineIsNotPropagated() {
+	resp, err := s.call(context.Background(), "")
+	require.NoError(s.T(), err, "call shouldn't fail")
+	assert.Empty(s.T(), resp.Header.Get(receivedTimeoutHeader), "no timeout should be sent")
+	assert.Empty(s.T(), resp.Header.Get(remainingMillisHeader), "the handler should have no deadline")
+}
+
+func (s *DeadlineSuite) TestExplicitHeaderIsKept() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := s.call(ctx, "500m")
+	require.NoError(s.T(), err, "call shouldn't fail")
+	assert.Equal(s.T(), "500m", resp.Header.Get(receivedTimeoutHeader), "an explicit timeout should not be overwritten")
+	assert.True(s.T(), s.remainingMillis(resp) <= 500, "the explicit timeout should apply")
+}
+
+func (s *DeadlineSuite) TestLongTimeoutsAreLimited() {
+	resp, err := s.call(context.Background(), "1H")
+	require.NoError(s.T(), err, "call shouldn't fail")
+	remaining := s.remainingMillis(resp)
+	assert.True(s.T(), remaining <= 10000, "the timeout should be limited to the maximum, got %dms", remaining)
+}
+
+func (s *DeadlineSuite) TestMalformedTimeoutIsIgnored() {
+	resp, err := s.call(context.Background(), "soon")
+	require.NoError(s.T(), err, "call shouldn't fail")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode, "the request should still be handled")
+	assert.Empty(s.T(), resp.Header.Get(remainingMillisHeader), "the handler should have no deadline")
+}
+
+func (s *DeadlineSuite) TestRequestsWithinTheSafetyMarginFailFast() {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := s.call(ctx, "")
+	require.Error(s.T(), err, "call should fail without being sent")
+	assert.Contains(s.T(), err.Error(), context.DeadlineExceeded.Error(), "the deadline should be reported as exceeded")
+}
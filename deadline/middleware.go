@@ -0,0 +1,43 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_deadline
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// TagForTimeout is the inbound tag holding the timeout requested by the client, as sent in the header.
+const TagForTimeout = "http.request.timeout"
+
+// Middleware returns a http.Handler middleware that applies timeouts sent by clients to the request's context.
+//
+// Requests without the header, or with a malformed one, are handled without a deadline being added.
+func Middleware(opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			value := req.Header.Get(o.headerName)
+			if value == "" {
+				next.ServeHTTP(resp, req)
+				return
+			}
+			timeout, err := parseTimeout(value)
+			if err != nil {
+				next.ServeHTTP(resp, req)
+				return
+			}
+			http_ctxtags.ExtractInbound(req).Set(TagForTimeout, value)
+			if o.maxTimeout > 0 && timeout > o.maxTimeout {
+				timeout = o.maxTimeout
+			}
+			ctx, cancel := context.WithTimeout(req.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(resp, req.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_deadline
+
+import "time"
+
+var (
+	defaultOptions = &options{
+		headerName: "X-Request-Timeout",
+	}
+)
+
+type options struct {
+	headerName   string
+	maxTimeout   time.Duration
+	safetyMargin time.Duration
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithHeaderName sets the name of the header that holds the timeout. The default is "X-Request-Timeout".
+//
+// Using "Grpc-Timeout" allows interoperating with gRPC clients and servers, e.g. through gRPC gateways.
+func WithHeaderName(name string) Option {
+	return func(o *options) {
+		o.headerName = name
+	}
+}
+
+// WithMaxTimeout limits the timeouts that the Middleware accepts from clients. Longer timeouts are shortened.
+//
+// By default timeouts are not limited.
+func WithMaxTimeout(max time.Duration) Option {
+	return func(o *options) {
+		o.maxTimeout = max
+	}
+}
+
+// WithSafetyMargin sets how much shorter than the remaining time the timeout sent by the Tripperware is, to account for
+// the time it takes to send the request and return the response.
+//
+// By default there is no margin.
+func WithSafetyMargin(margin time.Duration) Option {
+	return func(o *options) {
+		o.safetyMargin = margin
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_deadline
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	maxTimeoutDigits = 8
+	maxTimeoutValue  = 99999999
+	maxDuration      = time.Duration(1<<63 - 1)
+)
+
+var timeoutUnits = []struct {
+	unit     byte
+	duration time.Duration
+}{
+	{'n', time.Nanosecond},
+	{'u', time.Microsecond},
+	{'m', time.Millisecond},
+	{'S', time.Second},
+	{'M', time.Minute},
+	{'H', time.Hour},
+}
+
+// encodeTimeout encodes the timeout using the smallest unit that fits in 8 digits, rounding up.
+func encodeTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "0n"
+	}
+	for _, u := range timeoutUnits {
+		value := int64(timeout / u.duration)
+		if timeout%u.duration != 0 {
+			value++
+		}
+		if value <= maxTimeoutValue {
+			return strconv.FormatInt(value, 10) + string(u.unit)
+		}
+	}
+	return strconv.Itoa(maxTimeoutValue) + "H"
+}
+
+// parseTimeout decodes a timeout encoded by encodeTimeout.
+func parseTimeout(value string) (time.Duration, error) {
+	if len(value) < 2 || len(value) > maxTimeoutDigits+1 {
+		return 0, fmt.Errorf("malformed timeout %q", value)
+	}
+	digits, unit := value[:len(value)-1], value[len(value)-1]
+	for _, u := range timeoutUnits {
+		if u.unit != unit {
+			continue
+		}
+		n, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("malformed timeout %q", value)
+		}
+		if n > int64(maxDuration/u.duration) {
+			return maxDuration, nil
+		}
+		return time.Duration(n) * u.duration, nil
+	}
+	return 0, fmt.Errorf("unknown unit in timeout %q", value)
+}
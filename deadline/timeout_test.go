@@ -0,0 +1,60 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_deadline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeTimeout(t *testing.T) {
+	for _, tc := range []struct {
+		timeout  time.Duration
+		expected string
+	}{
+		{timeout: 0, expected: "0n"},
+		{timeout: -time.Second, expected: "0n"},
+		{timeout: 250 * time.Millisecond, expected: "250000u"},
+		{timeout: 99999999 * time.Nanosecond, expected: "99999999n"},
+		{timeout: 100 * time.Millisecond, expected: "100000u"},
+		{timeout: 100*time.Millisecond + time.Nanosecond, expected: "100001u"},
+		{timeout: 2 * time.Minute, expected: "120000m"},
+		{timeout: 48 * time.Hour, expected: "172800S"},
+		{timeout: maxDuration, expected: "2562048H"},
+	} {
+		assert.Equal(t, tc.expected, encodeTimeout(tc.timeout), "encoding of %v", tc.timeout)
+	}
+}
+
+func TestParseTimeout(t *testing.T) {
+	for value, expected := range map[string]time.Duration{
+		"0n":        0,
+		"250m":      250 * time.Millisecond,
+		"100000u":   100 * time.Millisecond,
+		"3S":        3 * time.Second,
+		"2M":        2 * time.Minute,
+		"1H":        time.Hour,
+		"99999999H": maxDuration,
+	} {
+		timeout, err := parseTimeout(value)
+		require.NoError(t, err, "parsing %q", value)
+		assert.Equal(t, expected, timeout, "parsing %q", value)
+	}
+	for _, value := range []string{"", "m", "1", "1x", "-1S", "1.5S", "123456789m"} {
+		_, err := parseTimeout(value)
+		assert.Error(t, err, "parsing %q should fail", value)
+	}
+}
+
+func TestEncodedTimeoutsParseBack(t *testing.T) {
+	for _, timeout := range []time.Duration{time.Nanosecond, 1234567 * time.Microsecond, 90 * time.Minute} {
+		parsed, err := parseTimeout(encodeTimeout(timeout))
+		require.NoError(t, err)
+		assert.True(t, parsed >= timeout, "timeouts should only ever be rounded up")
+		assert.True(t, parsed-timeout < timeout/1000+time.Nanosecond, "rounding should be small")
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_deadline
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/internal/header"
+)
+
+// Tripperware returns a new client-side ware that sends the deadline of the request's context to the server.
+//
+// Requests whose context has no deadline, or that already have the header set, are left alone. Requests that have
+// less time left than the safety margin (see `WithSafetyMargin`) fail with `context.DeadlineExceeded` without being
+// sent, as the server wouldn't be able to respond in time.
+func Tripperware(opts ...Option) httpwares.Tripperware {
+	o := evaluateOptions(opts)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			deadline, ok := req.Context().Deadline()
+			if !ok || req.Header.Get(o.headerName) != "" {
+				return next.RoundTrip(req)
+			}
+			timeout := deadline.Sub(time.Now()) - o.safetyMargin
+			if timeout <= 0 {
+				return nil, context.DeadlineExceeded
+			}
+			newReq := http_header.CloneRequestWithHeader(req)
+			newReq.Header.Set(o.headerName, encodeTimeout(timeout))
+			return next.RoundTrip(newReq)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_decompression
+
+import "io"
+
+// decodedBody decodes the body of a response as it is read, counting the bytes read from the wire.
+//
+// The decoder is created on the first read, as decoders read the header of the encoded data straight away.
+type decodedBody struct {
+	wire       io.ReadCloser
+	newDecoder DecoderFunc
+	decoder    io.ReadCloser
+	err        error
+	wireBytes  int
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.decoder == nil && b.err == nil {
+		b.decoder, b.err = b.newDecoder(&countingReader{r: b.wire, n: &b.wireBytes})
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.decoder.Read(p)
+}
+
+func (b *decodedBody) Close() error {
+	if b.decoder != nil {
+		b.decoder.Close()
+	}
+	return b.wire.Close()
+}
+
+// EncodedLength implements `http_metrics.EncodedBody`.
+func (b *decodedBody) EncodedLength() int {
+	return b.wireBytes
+}
+
+type countingReader struct {
+	r io.Reader
+	n *int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += n
+	return n, err
+}
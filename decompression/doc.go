@@ -0,0 +1,21 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_decompression` requests compressed responses and transparently decodes them.
+
+The `http.Transport` already does this for gzip, but only if the caller didn't set `Accept-Encoding` itself, and in a
+way that other tripperwares can't observe. The Tripperware negotiates `gzip` and `deflate` explicitly, and decodes
+the responses before they reach the tripperwares placed before it in the chain, so that e.g. the
+`http_logrus.ContentCaptureTripperware` sees the decoded body. Other encodings, such as brotli, can be added using
+`WithDecoder`.
+
+Decoded responses have `Uncompressed` set, and their `Content-Encoding` and `Content-Length` headers removed.
+
+Response sizes
+
+The decoded bodies implement `http_metrics.EncodedBody`, so that a `http_metrics.Tripperware` placed before this one in
+the chain reports both the decoded size of the response and its size on the wire to trackers implementing
+`http_metrics.EncodedSizeTracker`.
+*/
+package http_decompression
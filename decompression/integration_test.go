@@ -0,0 +1,163 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_decompression_test
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/decompression"
+	"github.com/improbable-eng/go-httpwares/metrics"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var content = strings.Repeat("decompress me, I'm very repetitive! ", 100)
+
+// encodingHandler encodes the response with the encoding given in the query, if the client accepts it.
+func encodingHandler(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("x-test-accept-encoding", req.Header.Get("Accept-Encoding"))
+	encoding := req.URL.Query().Get("encoding")
+	if !strings.Contains(req.Header.Get("Accept-Encoding"), encoding) {
+		encoding = ""
+	}
+	var w io.Writer = resp
+	switch encoding {
+	case "gzip", "x-custom":
+		gzipWriter := gzip.NewWriter(resp)
+		defer gzipWriter.Close()
+		w = gzipWriter
+	case "deflate":
+		zlibWriter := zlib.NewWriter(resp)
+		defer zlibWriter.Close()
+		w = zlibWriter
+	}
+	if encoding != "" {
+		resp.Header().Set("Content-Encoding", encoding)
+	}
+	resp.WriteHeader(http.StatusOK)
+	w.Write([]byte(content))
+}
+
+type sizeReporter struct {
+	mu           sync.Mutex
+	decodedSize  int
+	encodedSize  int
+	encodedKnown bool
+}
+
+func (r *sizeReporter) Track(req *http.Request) http_metrics.Tracker {
+	return r
+}
+
+func (r *sizeReporter) RequestStarted()                                                        {}
+func (r *sizeReporter) RequestRead(duration time.Duration, size int)                           {}
+func (r *sizeReporter) ResponseStarted(duration time.Duration, status int, header http.Header) {}
+
+func (r *sizeReporter) ResponseEncodedSize(size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.encodedSize = size
+	r.encodedKnown = true
+}
+
+func (r *sizeReporter) ResponseDone(duration time.Duration, status int, size int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decodedSize = size
+}
+
+func (r *sizeReporter) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decodedSize, r.encodedSize, r.encodedKnown = 0, 0, false
+}
+
+func TestDecompressionSuite(t *testing.T) {
+	reporter := &sizeReporter{}
+	s := &DecompressionSuite{
+		reporter: reporter,
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: http.HandlerFunc(encodingHandler),
+			ClientTripperware: []httpwares.Tripperware{
+				http_metrics.Tripperware(reporter),
+				http_decompression.Tripperware(http_decompression.WithDecoder("x-custom", func(r io.Reader) (io.ReadCloser, error) {
+					return gzip.NewReader(r)
+				})),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type DecompressionSuite struct {
+	*httpwares_testing.WaresTestSuite
+	reporter *sizeReporter
+}
+
+func (s *DecompressionSuite) SetupTest() {
+	s.reporter.reset()
+}
+
+func (s *DecompressionSuite) call(encoding string, acceptEncoding string) (*http.Response, string) {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl?encoding="+encoding, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(s.T(), err, "reading the body shouldn't fail")
+	return resp, string(body)
+}
+
+func (s *DecompressionSuite) TestNegotiatesEncodingsExplicitly() {
+	resp, _ := s.call("", "")
+	assert.Equal(s.T(), "x-custom, gzip, deflate", resp.Header.Get("x-test-accept-encoding"))
+}
+
+func (s *DecompressionSuite) TestDecodesResponses() {
+	for _, encoding := range []string{"gzip", "deflate", "x-custom"} {
+		resp, body := s.call(encoding, "")
+		assert.Equal(s.T(), content, body, "%v responses should be decoded", encoding)
+		assert.True(s.T(), resp.Uncompressed, "%v responses should be marked as uncompressed", encoding)
+		assert.Empty(s.T(), resp.Header.Get("Content-Encoding"), "%v responses should not claim to be encoded", encoding)
+		assert.Equal(s.T(), int64(-1), resp.ContentLength, "the length of decoded responses is unknown")
+	}
+}
+
+func (s *DecompressionSuite) TestReportsEncodedAndDecodedSizes() {
+	s.call("gzip", "")
+	s.reporter.mu.Lock()
+	defer s.reporter.mu.Unlock()
+	assert.Equal(s.T(), len(content), s.reporter.decodedSize, "the decoded size should be reported as the response size")
+	require.True(s.T(), s.reporter.encodedKnown, "the encoded size should be reported")
+	assert.True(s.T(), s.reporter.encodedSize > 0 && s.reporter.encodedSize < len(content)/10, "the encoded size should be the compressed one, got %d", s.reporter.encodedSize)
+}
+
+func (s *DecompressionSuite) TestUnencodedResponsesAreNotReportedAsEncoded() {
+	s.call("", "")
+	s.reporter.mu.Lock()
+	defer s.reporter.mu.Unlock()
+	assert.Equal(s.T(), len(content), s.reporter.decodedSize)
+	assert.False(s.T(), s.reporter.encodedKnown, "only decoded responses have an encoded size")
+}
+
+func (s *DecompressionSuite) TestCallersAcceptEncodingIsKept() {
+	resp, body := s.call("deflate", "deflate")
+	assert.Equal(s.T(), "deflate", resp.Header.Get("x-test-accept-encoding"), "the caller's Accept-Encoding should be sent")
+	assert.Equal(s.T(), "deflate", resp.Header.Get("Content-Encoding"), "the caller should get the encoded response")
+	assert.NotEqual(s.T(), content, body, "the caller should get the encoded response")
+}
@@ -0,0 +1,81 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_decompression
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+)
+
+var (
+	defaultOptions = &options{
+		decoders: []decoder{
+			{name: "gzip", new: newGzipReader},
+			{name: "deflate", new: zlib.NewReader},
+		},
+	}
+)
+
+type options struct {
+	decoders []decoder
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.decoders = append([]decoder(nil), defaultOptions.decoders...)
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// DecoderFunc creates a reader that decodes the data read from r.
+type DecoderFunc func(r io.Reader) (io.ReadCloser, error)
+
+type decoder struct {
+	name string
+	new  DecoderFunc
+}
+
+// WithDecoder adds an encoding (e.g. "br" for brotli) to the ones accepted, or replaces the built in one of the same
+// name. Encodings added with this option are listed before the built in ones in `Accept-Encoding`.
+func WithDecoder(name string, f DecoderFunc) Option {
+	return func(o *options) {
+		name = strings.ToLower(name)
+		decoders := []decoder{{name: name, new: f}}
+		for _, d := range o.decoders {
+			if d.name != name {
+				decoders = append(decoders, d)
+			}
+		}
+		o.decoders = decoders
+	}
+}
+
+func (o *options) acceptEncoding() string {
+	names := make([]string, 0, len(o.decoders))
+	for _, d := range o.decoders {
+		names = append(names, d.name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func (o *options) decoderFor(contentEncoding string) *decoder {
+	contentEncoding = strings.ToLower(strings.TrimSpace(contentEncoding))
+	for i := range o.decoders {
+		if o.decoders[i].name == contentEncoding {
+			return &o.decoders[i]
+		}
+	}
+	return nil
+}
+
+func newGzipReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
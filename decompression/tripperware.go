@@ -0,0 +1,43 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_decompression
+
+import (
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/internal/header"
+)
+
+// Tripperware returns a new client-side ware that requests compressed responses and decodes them.
+//
+// Requests that already have an `Accept-Encoding` header, or that ask for a `Range` of the content, are left alone,
+// and so are their responses, as the caller is expected to deal with the encoding itself.
+func Tripperware(opts ...Option) httpwares.Tripperware {
+	o := evaluateOptions(opts)
+	acceptEncoding := o.acceptEncoding()
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" {
+				return next.RoundTrip(req)
+			}
+			newReq := http_header.CloneRequestWithHeader(req)
+			newReq.Header.Set("Accept-Encoding", acceptEncoding)
+			resp, err := next.RoundTrip(newReq)
+			if err != nil || resp.Body == nil {
+				return resp, err
+			}
+			d := o.decoderFor(resp.Header.Get("Content-Encoding"))
+			if d == nil || req.Method == "HEAD" {
+				return resp, err
+			}
+			resp.Body = &decodedBody{wire: resp.Body, newDecoder: d.new}
+			resp.Header.Del("Content-Encoding")
+			resp.Header.Del("Content-Length")
+			resp.ContentLength = -1
+			resp.Uncompressed = true
+			return resp, nil
+		})
+	}
+}
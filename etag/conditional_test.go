@@ -0,0 +1,69 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_etag
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeETag(t *testing.T) {
+	strong := computeETag([]byte("some body"), false)
+	assert.Regexp(t, `^"[0-9a-f]{40}"$`, strong)
+	assert.Equal(t, "W/"+strong, computeETag([]byte("some body"), true))
+	assert.NotEqual(t, strong, computeETag([]byte("other body"), false))
+}
+
+func TestNotModified(t *testing.T) {
+	for _, tcase := range []struct {
+		name     string
+		request  map[string]string
+		response map[string]string
+		expected bool
+	}{
+		{"no conditions", map[string]string{}, map[string]string{"ETag": `"a"`}, false},
+		{"matching etag", map[string]string{"If-None-Match": `"a"`}, map[string]string{"ETag": `"a"`}, true},
+		{"matching etag in list", map[string]string{"If-None-Match": `"b", "a"`}, map[string]string{"ETag": `"a"`}, true},
+		{"weak comparison", map[string]string{"If-None-Match": `W/"a"`}, map[string]string{"ETag": `"a"`}, true},
+		{"wildcard", map[string]string{"If-None-Match": `*`}, map[string]string{"ETag": `"a"`}, true},
+		{"different etag", map[string]string{"If-None-Match": `"b"`}, map[string]string{"ETag": `"a"`}, false},
+		{"no etag", map[string]string{"If-None-Match": `"a"`}, map[string]string{}, false},
+		{
+			"etag takes precedence over date",
+			map[string]string{"If-None-Match": `"b"`, "If-Modified-Since": "Mon, 02 Jan 2017 15:04:05 GMT"},
+			map[string]string{"ETag": `"a"`, "Last-Modified": "Mon, 02 Jan 2017 15:04:05 GMT"},
+			false,
+		},
+		{
+			"not modified since",
+			map[string]string{"If-Modified-Since": "Mon, 02 Jan 2017 15:04:05 GMT"},
+			map[string]string{"Last-Modified": "Mon, 02 Jan 2017 15:04:05 GMT"},
+			true,
+		},
+		{
+			"modified since",
+			map[string]string{"If-Modified-Since": "Mon, 02 Jan 2017 15:04:05 GMT"},
+			map[string]string{"Last-Modified": "Mon, 02 Jan 2017 15:04:06 GMT"},
+			false,
+		},
+		{
+			"invalid date",
+			map[string]string{"If-Modified-Since": "yesterday"},
+			map[string]string{"Last-Modified": "Mon, 02 Jan 2017 15:04:05 GMT"},
+			false,
+		},
+	} {
+		req, _ := http.NewRequest("GET", "http://something.local/", nil)
+		for k, v := range tcase.request {
+			req.Header.Set(k, v)
+		}
+		h := http.Header{}
+		for k, v := range tcase.response {
+			h.Set(k, v)
+		}
+		assert.Equal(t, tcase.expected, notModified(req, h), tcase.name)
+	}
+}
@@ -0,0 +1,18 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_etag` adds ETags to responses and answers conditional GET requests.
+
+The Middleware buffers the response of the handler, computes an ETag from its body (unless the handler set one
+itself), and replies with `304 Not Modified` and no body if it matches the `If-None-Match` header of the request.
+Handlers that set a `Last-Modified` header get `If-Modified-Since` handled as well.
+
+Only complete `2xx` responses to `GET` and `HEAD` requests are considered. Responses that are streamed (the handler
+flushes them), or that are larger than the maximum body size, are passed through untouched, which makes it safe to put
+in front of existing handlers.
+
+When used together with `http_compression`, this middleware should come before it, so that each encoding of a response
+gets its own ETag.
+*/
+package http_etag
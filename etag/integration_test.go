@@ -0,0 +1,172 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_etag_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/etag"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const content = "some content worth caching"
+
+// contentHandler writes responses of different kinds, depending on the query parameters.
+func contentHandler(resp http.ResponseWriter, req *http.Request) {
+	resp.Header().Set("Content-Type", "text/plain")
+	switch req.URL.Query().Get("mode") {
+	case "own_etag":
+		resp.Header().Set("ETag", `"handler-etag"`)
+		resp.Write([]byte(content))
+	case "last_modified":
+		resp.Header().Set("Last-Modified", "Mon, 02 Jan 2017 15:04:05 GMT")
+		resp.Write([]byte(content))
+	case "large":
+		resp.Write([]byte(strings.Repeat(content, 10)))
+	case "streaming":
+		if _, isFlusher := resp.(http.Flusher); !isFlusher {
+			resp.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		for i := 0; i < 4; i++ {
+			resp.Write([]byte(content))
+			resp.(http.Flusher).Flush()
+		}
+	case "not_found":
+		resp.WriteHeader(http.StatusNotFound)
+		resp.Write([]byte(content))
+	default:
+		resp.Write([]byte(content))
+	}
+}
+
+func TestETagSuite(t *testing.T) {
+	s := &ETagSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: http.HandlerFunc(contentHandler),
+			ServerMiddleware: []httpwares.Middleware{
+				http_etag.Middleware(http_etag.WithMaxBodySize(100)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type ETagSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *ETagSuite) call(method string, mode string, headers ...string) (*http.Response, string) {
+	return call(s.WaresTestSuite, method, mode, headers...)
+}
+
+func call(s *httpwares_testing.WaresTestSuite, method string, mode string, headers ...string) (*http.Response, string) {
+	req, _ := http.NewRequest(method, "https://something.local/someurl?mode="+mode, nil)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(s.T(), err, "reading the body shouldn't fail")
+	return resp, string(body)
+}
+
+func (s *ETagSuite) TestETagIsComputed() {
+	resp, body := s.call("GET", "")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), content, body)
+	assert.Regexp(s.T(), `^"[0-9a-f]+"$`, resp.Header.Get("ETag"))
+	assert.EqualValues(s.T(), len(content), resp.ContentLength, "buffered responses must have a content length")
+
+	again, _ := s.call("GET", "")
+	assert.Equal(s.T(), resp.Header.Get("ETag"), again.Header.Get("ETag"), "the etag must be stable")
+}
+
+func (s *ETagSuite) TestMatchingETagIsNotModified() {
+	first, _ := s.call("GET", "")
+	resp, body := s.call("GET", "", "If-None-Match", first.Header.Get("ETag"))
+	assert.Equal(s.T(), http.StatusNotModified, resp.StatusCode)
+	assert.Empty(s.T(), body)
+	assert.Equal(s.T(), first.Header.Get("ETag"), resp.Header.Get("ETag"))
+	assert.Empty(s.T(), resp.Header.Get("Content-Type"))
+}
+
+func (s *ETagSuite) TestDifferentETagIsSentInFull() {
+	resp, body := s.call("GET", "", "If-None-Match", `"something-else"`)
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), content, body)
+}
+
+func (s *ETagSuite) TestHandlerETagIsUsed() {
+	resp, body := s.call("GET", "own_etag")
+	assert.Equal(s.T(), `"handler-etag"`, resp.Header.Get("ETag"))
+	assert.Equal(s.T(), content, body)
+
+	resp, body = s.call("GET", "own_etag", "If-None-Match", `"handler-etag"`)
+	assert.Equal(s.T(), http.StatusNotModified, resp.StatusCode)
+	assert.Empty(s.T(), body)
+
+	resp, _ = s.call("HEAD", "own_etag", "If-None-Match", `"handler-etag"`)
+	assert.Equal(s.T(), http.StatusNotModified, resp.StatusCode)
+}
+
+func (s *ETagSuite) TestLastModifiedIsHandled() {
+	resp, body := s.call("GET", "last_modified", "If-Modified-Since", "Mon, 02 Jan 2017 15:04:05 GMT")
+	assert.Equal(s.T(), http.StatusNotModified, resp.StatusCode)
+	assert.Empty(s.T(), body)
+
+	resp, body = s.call("GET", "last_modified", "If-Modified-Since", "Mon, 02 Jan 2017 15:04:04 GMT")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), content, body)
+}
+
+func (s *ETagSuite) TestSkippedResponses() {
+	for _, mode := range []string{"large", "streaming", "not_found"} {
+		resp, body := s.call("GET", mode, "If-None-Match", "*")
+		assert.NotEqual(s.T(), http.StatusNotModified, resp.StatusCode, "mode %v must not be answered with not modified", mode)
+		assert.Empty(s.T(), resp.Header.Get("ETag"), "mode %v must not have an etag", mode)
+		assert.Contains(s.T(), body, content, "mode %v must be sent in full", mode)
+	}
+}
+
+func (s *ETagSuite) TestWritesAreNotAffected() {
+	req, _ := http.NewRequest("POST", "https://something.local/someurl", strings.NewReader("data"))
+	req.Header.Set("If-None-Match", "*")
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Empty(s.T(), resp.Header.Get("ETag"))
+}
+
+func TestWeakETags(t *testing.T) {
+	s := &WeakETagSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler:          http.HandlerFunc(contentHandler),
+			ServerMiddleware: []httpwares.Middleware{http_etag.Middleware(http_etag.WithWeakETags())},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type WeakETagSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *WeakETagSuite) TestWeakETagIsComputed() {
+	resp, _ := call(s.WaresTestSuite, "GET", "")
+	assert.Regexp(s.T(), `^W/"[0-9a-f]+"$`, resp.Header.Get("ETag"))
+
+	resp, body := call(s.WaresTestSuite, "GET", "", "If-None-Match", resp.Header.Get("ETag"))
+	assert.Equal(s.T(), http.StatusNotModified, resp.StatusCode)
+	assert.Empty(s.T(), body)
+}
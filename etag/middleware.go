@@ -0,0 +1,28 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_etag
+
+import (
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+)
+
+// Middleware returns a http.Handler middleware that adds ETags to responses and handles conditional requests.
+func Middleware(opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			if req.Method != "GET" && req.Method != "HEAD" {
+				next.ServeHTTP(resp, req)
+				return
+			}
+			wrapped := httpwares.WrapResponseWriter(resp)
+			filter := newETagFilter(wrapped, req, o)
+			next.ServeHTTP(httpwares.WrapResponseWriterWithFilter(wrapped, filter), req)
+			// The handler has returned, so a failure to send the buffered response can't be reported to it.
+			filter.close()
+		})
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_etag
+
+var (
+	defaultOptions = &options{
+		weak:        false,
+		maxBodySize: 1 << 20,
+	}
+)
+
+type options struct {
+	weak        bool
+	maxBodySize int
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithWeakETags makes the middleware compute weak ETags (e.g. `W/"..."`) instead of strong ones.
+//
+// Weak ETags should be used if responses that are equivalent can differ byte-wise, for example if they are compressed
+// by a later middleware.
+func WithWeakETags() Option {
+	return func(o *options) {
+		o.weak = true
+	}
+}
+
+// WithMaxBodySize sets the size in bytes of the largest response body that is buffered to compute its ETag. The
+// default is 1MB.
+//
+// Larger responses are sent as they are.
+func WithMaxBodySize(bytes int) Option {
+	return func(o *options) {
+		o.maxBodySize = bytes
+	}
+}
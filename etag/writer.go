@@ -0,0 +1,115 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_etag
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/improbable-eng/go-httpwares"
+)
+
+// etagFilter buffers the response written by the handler, to send it with an ETag once it is complete.
+//
+// Responses that don't qualify are passed through as soon as that is known.
+type etagFilter struct {
+	resp        httpwares.WrappedResponseWriter
+	req         *http.Request
+	opts        *options
+	code        int
+	wroteHeader bool
+	passThrough bool
+	notModified bool
+	buf         []byte
+}
+
+func newETagFilter(resp httpwares.WrappedResponseWriter, req *http.Request, opts *options) *etagFilter {
+	return &etagFilter{resp: resp, req: req, opts: opts}
+}
+
+func (w *etagFilter) WriteHeader(code int) {
+	w.wroteHeader = true
+	w.code = code
+	h := w.resp.Header()
+	if code < 200 || code > 299 || code == http.StatusNoContent || code == http.StatusPartialContent {
+		w.startPassThrough()
+		return
+	}
+	if h.Get("ETag") != "" || h.Get("Last-Modified") != "" || w.req.Method == "HEAD" {
+		// Nothing to compute, so there is no need to buffer the response.
+		if notModified(w.req, h) {
+			w.notModified = true
+			writeNotModified(w.resp)
+			return
+		}
+		w.startPassThrough()
+	}
+}
+
+func (w *etagFilter) Write(buf []byte) (int, error) {
+	if w.notModified {
+		return len(buf), nil // the client has the body already
+	}
+	if w.passThrough {
+		return w.resp.Write(buf)
+	}
+	if len(w.buf)+len(buf) > w.opts.maxBodySize {
+		if err := w.startPassThrough(); err != nil {
+			return 0, err
+		}
+		return w.resp.Write(buf)
+	}
+	w.buf = append(w.buf, buf...)
+	return len(buf), nil
+}
+
+// Flush streams the response, which can't have an ETag computed then.
+func (w *etagFilter) Flush() {
+	if !w.passThrough && !w.notModified {
+		w.startPassThrough()
+	}
+	if flusher, ok := w.resp.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// startPassThrough sends the headers and the response buffered so far, and the rest of the response as it comes.
+func (w *etagFilter) startPassThrough() error {
+	w.passThrough = true
+	buf := w.buf
+	w.buf = nil
+	w.resp.WriteHeader(w.code)
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.resp.Write(buf)
+	return err
+}
+
+// close finishes the response once the handler returned.
+func (w *etagFilter) close() error {
+	if !w.wroteHeader || w.passThrough || w.notModified {
+		return nil
+	}
+	h := w.resp.Header()
+	h.Set("ETag", computeETag(w.buf, w.opts.weak))
+	if notModified(w.req, h) {
+		writeNotModified(w.resp)
+		return nil
+	}
+	if h.Get("Content-Length") == "" {
+		h.Set("Content-Length", strconv.Itoa(len(w.buf)))
+	}
+	w.resp.WriteHeader(w.code)
+	_, err := w.resp.Write(w.buf)
+	return err
+}
+
+func writeNotModified(resp http.ResponseWriter) {
+	h := resp.Header()
+	// As in http.ServeContent, headers describing the body that isn't sent are removed.
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	resp.WriteHeader(http.StatusNotModified)
+}
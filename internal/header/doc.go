@@ -0,0 +1,6 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// Package http_header holds helpers for copying the headers of outbound requests, shared by the tripperwares of this
+// repository.
+package http_header
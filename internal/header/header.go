@@ -0,0 +1,23 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_header
+
+import "net/http"
+
+// CloneRequestWithHeader returns a copy of the request with a copy of its Header, which can be modified before the
+// request is passed on, as RoundTrippers must not modify the requests they are given.
+func CloneRequestWithHeader(req *http.Request) *http.Request {
+	newReq := req.WithContext(req.Context()) // make a copy.
+	newReq.Header = Clone(req.Header)
+	return newReq
+}
+
+// Clone returns a deep copy of the header.
+func Clone(h http.Header) http.Header {
+	clone := make(http.Header, len(h))
+	for k, v := range h {
+		clone[k] = append([]string(nil), v...)
+	}
+	return clone
+}
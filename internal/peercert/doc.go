@@ -0,0 +1,6 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// Package http_peercert holds helpers for identifying the peers of TLS connections by their certificates, shared by
+// the middlewares of this repository.
+package http_peercert
@@ -0,0 +1,6 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// Package http_requestbody holds helpers for reading the bodies of outbound requests more than once, shared by the
+// tripperwares of this repository.
+package http_requestbody
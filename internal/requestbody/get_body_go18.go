@@ -1,6 +1,6 @@
 // +build go1.8
 
-package http_retry
+package http_requestbody
 
 import (
 	"bytes"
@@ -9,8 +9,8 @@ import (
 	"net/http"
 )
 
-// Wrap the Body of the Request so it can be read repeatedly in case of retrying
-func getBody(r *http.Request) func() (io.ReadCloser, error) {
+// GetBody wraps the Body of the Request so it can be read repeatedly, e.g. to retry or sign it.
+func GetBody(r *http.Request) func() (io.ReadCloser, error) {
 	if r.GetBody != nil {
 		return r.GetBody
 	} else if r.Body != nil {
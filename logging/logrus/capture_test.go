@@ -2,6 +2,7 @@ package http_logrus_test
 
 import (
 	"bytes"
+	"encoding/base64"
 	"net/http"
 	"runtime"
 	"strings"
@@ -132,3 +133,27 @@ func (s *logrusContentCaptureSuite) TestCapture_ChunkResponse() {
 	assert.Contains(s.T(), clientMsgs[1], `"response body capture skipped, content length negative"`, "client side should log a helpful message")
 	assert.Contains(s.T(), serverMsgs[1], `"response body capture skipped, transfer encoding is not identity"`, "server side should log a helpful message about skipped body")
 }
+
+func (s *logrusContentCaptureSuite) TestCapture_DecodedResponse() {
+	req, _ := http.NewRequest("GET", "https://fakeaddress.fakeaddress.com/capture/request/gzipped", nil)
+	_, clientMsgs := s.getServerAndClientLogs(req, 1, 1)
+	require.Len(s.T(), clientMsgs, 1)
+	assert.Contains(s.T(), clientMsgs[0], `"http.response.body_raw": "`+base64.StdEncoding.EncodeToString([]byte("gzipped value"))+`"`, "the decoded response should be captured")
+}
+
+func (s *logrusContentCaptureSuite) TestCapture_LargeDecodedResponseIsSkipped() {
+	req, _ := http.NewRequest("GET", "https://fakeaddress.fakeaddress.com/capture/request/gzipped_large", nil)
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	content, err := ioutil.ReadAll(resp.Body)
+	require.NoError(s.T(), err, "reading the body shouldn't fail")
+	resp.Body.Close()
+	assert.Equal(s.T(), gzippedLargeContent, string(content), "the whole decoded response should be readable")
+	msgs := s.getOutputJSONs()
+	require.Len(s.T(), msgs, 2, "the client and the server should each log one message")
+	for _, m := range msgs {
+		if strings.Contains(m, `"span.kind": "client"`) {
+			assert.Contains(s.T(), m, "response body capture skipped, decoded content longer than", "client side should log a helpful message")
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"net/http"
 
@@ -15,7 +16,9 @@ import (
 // ContentCaptureTripperware is a client-side http ware for logging contents of HTTP requests and responses (body and headers).
 //
 // Only requests with a set GetBody field will be captured (strings, bytes etc).
-// Only responses with Content-Length are captured, with no support for chunk-encoded responses.
+// Only responses with Content-Length are captured, with no support for chunk-encoded responses. Responses decoded by
+// the transport or the `http_decompression.Tripperware` (placed after this one) are captured in their decoded form, as
+// long as they are no longer than 64KB.
 //
 // The body will be recorded as a separate log message. Body of `application/json` will be captured as
 // http.request.body_json (in structured JSON form) and others will be captured as http.request.body_raw logrus field
@@ -69,8 +72,10 @@ func captureTripperwareRequestContent(req *http.Request, entry *logrus.Entry) er
 }
 
 func captureTripperwareResponseContent(resp *http.Response, entry *logrus.Entry) error {
+	if resp.ContentLength < 0 && resp.Uncompressed {
+		return captureTripperwareDecodedResponseContent(resp, entry)
+	}
 	if resp.ContentLength <= 0 {
-		// TODO(mwitkow): Deal with response.Uncompressed and gzip encoding (Content Length -1).
 		if resp.ContentLength != 0 {
 			entry.Infof("response body capture skipped, content length negative")
 		}
@@ -82,10 +87,39 @@ func captureTripperwareResponseContent(resp *http.Response, entry *logrus.Entry)
 	}
 	// Make sure we give the Response back its body so the client can read it.
 	resp.Body = ioutil.NopCloser(bytes.NewReader(content))
+	logResponseContent(resp, content, entry)
+	return nil
+}
+
+// captureTripperwareDecodedResponseContent captures responses of unknown length that were decoded, reading no more
+// than maxDecodedResponseCapture bytes of them.
+func captureTripperwareDecodedResponseContent(resp *http.Response, entry *logrus.Entry) error {
+	content, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxDecodedResponseCapture+1))
+	if err != nil {
+		return err
+	}
+	// Make sure we give the Response back its body, including the part that was read, so the client can read it.
+	resp.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(content), resp.Body), Closer: resp.Body}
+	if len(content) > maxDecodedResponseCapture {
+		entry.Infof("response body capture skipped, decoded content longer than %d bytes", maxDecodedResponseCapture)
+		return nil
+	}
+	logResponseContent(resp, content, entry)
+	return nil
+}
+
+func logResponseContent(resp *http.Response, content []byte, entry *logrus.Entry) {
 	if headerIsJson(resp.Header) {
 		entry.WithField("http.response.body_json", json.RawMessage(content)).Info("request body captured in http.response.body_json field")
 	} else {
 		entry.WithField("http.response.body_raw", base64.StdEncoding.EncodeToString(content)).Info("request body captured in http.response.body_raw field")
 	}
-	return nil
+}
+
+// maxDecodedResponseCapture is the longest decoded response that is captured, as their length isn't known upfront.
+const maxDecodedResponseCapture = 64 * 1024
+
+type prefixedBody struct {
+	io.Reader
+	io.Closer
 }
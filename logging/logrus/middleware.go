@@ -67,6 +67,9 @@ func responseFields(wrappedResp httpwares.WrappedResponseWriter, startTime time.
 		"http.response.status":       wrappedResp.StatusCode(),
 		"http.response.length_bytes": wrappedResp.MessageLength(),
 	}
+	if decoded := httpwares.DecodedMessageLength(wrappedResp); decoded != wrappedResp.MessageLength() {
+		postCallFields["http.response.uncompressed_length_bytes"] = decoded // e.g. compressed by http_compression
+	}
 	return postCallFields
 }
 
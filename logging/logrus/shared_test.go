@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"io"
 	"time"
@@ -45,6 +46,8 @@ func handlerForTestingOfCaptures(t *testing.T) http.Handler {
 	m := http.NewServeMux()
 	m.HandleFunc("/capture/request/chunked", handlerChunked())
 	m.HandleFunc("/capture/request/plain", handlerPlainText())
+	m.HandleFunc("/capture/request/gzipped", handlerGzipped())
+	m.HandleFunc("/capture/request/gzipped_large", handlerGzippedLarge())
 	m.Handle("/", &loggingHandler{t})
 	return m
 }
@@ -71,6 +74,31 @@ func handlerChunked() http.HandlerFunc {
 	}
 }
 
+func handlerGzipped() http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("content-type", "text/plain")
+		resp.Header().Set("content-encoding", "gzip")
+		resp.WriteHeader(200)
+		gzipWriter := gzip.NewWriter(resp)
+		gzipWriter.Write([]byte("gzipped value"))
+		gzipWriter.Close()
+	}
+}
+
+// gzippedLargeContent is longer than the decoded responses the ContentCaptureTripperware captures.
+var gzippedLargeContent = strings.Repeat("gzipped value", 10000)
+
+func handlerGzippedLarge() http.HandlerFunc {
+	return func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("content-type", "text/plain")
+		resp.Header().Set("content-encoding", "gzip")
+		resp.WriteHeader(200)
+		gzipWriter := gzip.NewWriter(resp)
+		gzipWriter.Write([]byte(gzippedLargeContent))
+		gzipWriter.Close()
+	}
+}
+
 type logrusBaseTestSuite struct {
 	*httpwares_testing.WaresTestSuite
 	buffer         *bytes.Buffer
@@ -8,5 +8,12 @@ The middleware (server-side) or tripperware (client-side) must be given a report
 
 Prometheus-based reporter implementations for client and server metrics are included. The user may choose what level of
 detail is included using options to these reporters.
+
+Reporters can additionally implement `LimitReporter` to record the state of concurrency limits of other middleware. The
+Prometheus server-side reporter does, exporting the current limit and the number of queued requests as gauges.
+
+Trackers can implement `EncodedSizeTracker` to record the size of responses on the wire when they were decoded by
+another tripperware, such as `http_decompression`, or compressed by another middleware, such as `http_compression`.
+The Prometheus reporters do, when recording sizes.
 */
 package http_metrics
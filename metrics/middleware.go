@@ -29,8 +29,15 @@ func Middleware(reporter Reporter) httpwares.Middleware {
 			wrapped := wrapWriter(resp, func(status int) {
 				tracker.ResponseStarted(time.Since(start), status, resp.Header())
 			})
-			next.ServeHTTP(wrapped, req)
-			tracker.ResponseDone(time.Since(start), wrapped.Status(), wrapped.Size())
+			// Wrapped so that encoding middleware further down the chain (e.g. compression) can report the size of the
+			// response before it was encoded, see `httpwares.DecodedMessageLength`.
+			handlerResp := httpwares.WrapResponseWriter(wrapped)
+			next.ServeHTTP(handlerResp, req)
+			decodedSize := httpwares.DecodedMessageLength(handlerResp)
+			if encodedTracker, ok := tracker.(EncodedSizeTracker); ok && decodedSize != handlerResp.MessageLength() {
+				encodedTracker.ResponseEncodedSize(wrapped.Size())
+			}
+			tracker.ResponseDone(time.Since(start), wrapped.Status(), decodedSize)
 		})
 	}
 }
@@ -52,6 +52,14 @@ var (
 		},
 		[]string{"name", "handler", "host", "path", "method", "status"},
 	)
+	clientResponseEncodedSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_tripper_response_encoded_size_bytes",
+			Help:    "Size of received responses before decoding, for responses that were decoded.",
+			Buckets: prometheus.ExponentialBuckets(32, 32, 6),
+		},
+		[]string{"name", "handler", "host", "path", "method", "status"},
+	)
 
 	clientInit     sync.Once
 	clientHistInit sync.Once
@@ -73,6 +81,7 @@ func ClientMetrics(opts ...opt) http_metrics.Reporter {
 		clientSizeInit.Do(func() {
 			prometheus.MustRegister(clientRequestSize)
 			prometheus.MustRegister(clientResponseSize)
+			prometheus.MustRegister(clientResponseEncodedSize)
 		})
 	}
 	return &clientReporter{opts: o}
@@ -92,6 +101,8 @@ func (r *clientReporter) Track(req *http.Request) http_metrics.Tracker {
 type clientTracker struct {
 	opts *options
 	*meta
+	encodedSize int
+	encoded     bool
 }
 
 func (t *clientTracker) RequestStarted() {
@@ -112,8 +123,17 @@ func (t *clientTracker) ResponseStarted(duration time.Duration, code int, header
 	}
 }
 
+func (t *clientTracker) ResponseEncodedSize(size int) {
+	t.encodedSize = size
+	t.encoded = true
+}
+
 func (t *clientTracker) ResponseDone(duration time.Duration, code int, size int) {
 	if t.opts.sizes {
-		clientResponseSize.WithLabelValues(t.name, t.handler, t.host, t.path, t.method, strconv.Itoa(code)).Observe(float64(size))
+		status := strconv.Itoa(code)
+		clientResponseSize.WithLabelValues(t.name, t.handler, t.host, t.path, t.method, status).Observe(float64(size))
+		if t.encoded {
+			clientResponseEncodedSize.WithLabelValues(t.name, t.handler, t.host, t.path, t.method, status).Observe(float64(t.encodedSize))
+		}
 	}
 }
@@ -53,10 +53,34 @@ var (
 		},
 		[]string{"name", "handler", "host", "path", "method", "status"},
 	)
+	serverResponseEncodedSize = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_handler_response_encoded_size_bytes",
+			Help:    "Size of sent responses after encoding, for responses that were encoded.",
+			Buckets: prometheus.ExponentialBuckets(32, 32, 6),
+		},
+		[]string{"name", "handler", "host", "path", "method", "status"},
+	)
+
+	serverConcurrencyLimit = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_handler_concurrency_limit",
+			Help: "Current limit of concurrent requests.",
+		},
+		[]string{"name", "group"},
+	)
+	serverQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_handler_queued_requests",
+			Help: "Number of requests waiting for the concurrency limit.",
+		},
+		[]string{"name", "group"},
+	)
 
-	serverInit     sync.Once
-	serverHistInit sync.Once
-	serverSizeInit sync.Once
+	serverInit      sync.Once
+	serverHistInit  sync.Once
+	serverSizeInit  sync.Once
+	serverLimitInit sync.Once
 )
 
 func ServerMetrics(opts ...opt) http_metrics.Reporter {
@@ -74,6 +98,7 @@ func ServerMetrics(opts ...opt) http_metrics.Reporter {
 		serverSizeInit.Do(func() {
 			prometheus.MustRegister(serverRequestSize)
 			prometheus.MustRegister(serverResponseSize)
+			prometheus.MustRegister(serverResponseEncodedSize)
 		})
 	}
 	return &serverReporter{opts: o}
@@ -83,6 +108,27 @@ type serverReporter struct {
 	opts *options
 }
 
+// LimitChanged implements http_metrics.LimitReporter.
+func (r *serverReporter) LimitChanged(handlerGroup string, limit int) {
+	registerLimitMetrics()
+	serverConcurrencyLimit.WithLabelValues(r.opts.name, handlerGroup).Set(float64(limit))
+}
+
+// QueueDepthChanged implements http_metrics.LimitReporter.
+func (r *serverReporter) QueueDepthChanged(handlerGroup string, depth int) {
+	registerLimitMetrics()
+	serverQueueDepth.WithLabelValues(r.opts.name, handlerGroup).Set(float64(depth))
+}
+
+// registerLimitMetrics registers the concurrency limit metrics on first use, so that they are only exported by
+// servers that limit concurrency.
+func registerLimitMetrics() {
+	serverLimitInit.Do(func() {
+		prometheus.MustRegister(serverConcurrencyLimit)
+		prometheus.MustRegister(serverQueueDepth)
+	})
+}
+
 func (r *serverReporter) Track(req *http.Request) http_metrics.Tracker {
 	return &serverTracker{
 		opts: r.opts,
@@ -93,6 +139,8 @@ func (r *serverReporter) Track(req *http.Request) http_metrics.Tracker {
 type serverTracker struct {
 	opts *options
 	*meta
+	encodedSize int
+	encoded     bool
 }
 
 func (t *serverTracker) RequestStarted() {
@@ -108,6 +156,11 @@ func (t *serverTracker) RequestRead(duration time.Duration, size int) {
 func (t *serverTracker) ResponseStarted(duration time.Duration, code int, header http.Header) {
 }
 
+func (t *serverTracker) ResponseEncodedSize(size int) {
+	t.encodedSize = size
+	t.encoded = true
+}
+
 func (t *serverTracker) ResponseDone(duration time.Duration, code int, size int) {
 	status := strconv.Itoa(code)
 	serverCompleted.WithLabelValues(t.name, t.handler, t.host, t.path, t.method, status).Inc()
@@ -116,5 +169,8 @@ func (t *serverTracker) ResponseDone(duration time.Duration, code int, size int)
 	}
 	if t.opts.sizes {
 		serverResponseSize.WithLabelValues(t.name, t.handler, t.host, t.path, t.method, strconv.Itoa(code)).Observe(float64(size))
+		if t.encoded {
+			serverResponseEncodedSize.WithLabelValues(t.name, t.handler, t.host, t.path, t.method, strconv.Itoa(code)).Observe(float64(t.encodedSize))
+		}
 	}
 }
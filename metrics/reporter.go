@@ -33,3 +33,27 @@ type Tracker interface {
 	// On the server, this is called when the handler returns and has therefore completed writing the response.
 	ResponseDone(duration time.Duration, status int, size int)
 }
+
+// EncodedSizeTracker is optionally implemented by Trackers that record the size of responses as sent on the wire,
+// when it differs from their decoded size (e.g. when decoded by `http_decompression.Tripperware` on the client, or
+// compressed by `http_compression.Middleware` on the server).
+type EncodedSizeTracker interface {
+	// The response body was encoded. This is called right before Tracker.ResponseDone, which receives the decoded size.
+	ResponseEncodedSize(size int)
+}
+
+// EncodedBody is implemented by response bodies that are decoded from the body received on the wire, allowing its size
+// to be reported to EncodedSizeTrackers.
+type EncodedBody interface {
+	// EncodedLength returns the number of bytes of the encoded body read so far.
+	EncodedLength() int
+}
+
+// LimitReporter is optionally implemented by Reporters that record the state of server-side concurrency limits, such
+// as the ones of `http_concurrency.Middleware`.
+type LimitReporter interface {
+	// The limit of concurrent requests for the given handler group has changed.
+	LimitChanged(handlerGroup string, limit int)
+	// The number of requests queued for the given handler group has changed.
+	QueueDepthChanged(handlerGroup string, depth int)
+}
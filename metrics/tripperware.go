@@ -13,6 +13,8 @@ import (
 
 // Tripperware returns a new client-side ware that exports request metrics.
 // If the tags tripperware is used, this should be placed after tags to pick up metadata.
+// If the decompression tripperware is used, this should be placed before it to report both the decoded and encoded
+// sizes of responses.
 func Tripperware(reporter Reporter) httpwares.Tripperware {
 	return func(next http.RoundTripper) http.RoundTripper {
 		if reporter == nil {
@@ -53,7 +55,11 @@ func Tripperware(reporter Reporter) httpwares.Tripperware {
 				return resp, err
 			}
 			tracker.ResponseStarted(dur, resp.StatusCode, resp.Header)
+			encoded, _ := resp.Body.(EncodedBody)
 			resp.Body = wrapBody(resp.Body, func(size int) {
+				if encodedTracker, ok := tracker.(EncodedSizeTracker); ok && encoded != nil {
+					encodedTracker.ResponseEncodedSize(encoded.EncodedLength())
+				}
 				tracker.ResponseDone(time.Since(start), resp.StatusCode, size)
 			})
 			return resp, err
@@ -8,11 +8,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/compression"
 	"github.com/improbable-eng/go-httpwares/metrics"
 	"github.com/improbable-eng/go-httpwares/metrics/prometheus"
 	"github.com/improbable-eng/go-httpwares/tags"
@@ -127,3 +129,34 @@ func ExampleTripperware() {
 	)
 	c.Get("example.org/foo")
 }
+
+type encodedSizeReporter struct {
+	testReporter
+	encodedsize int
+}
+
+func (r *encodedSizeReporter) Track(req *http.Request) http_metrics.Tracker {
+	r.tracked += 1
+	return r
+}
+
+func (r *encodedSizeReporter) ResponseEncodedSize(size int) {
+	r.encodedsize = size
+}
+
+func TestMiddleware_ReportsEncodedSize(t *testing.T) {
+	r := &encodedSizeReporter{}
+	content := strings.Repeat("resp-body", 1000)
+	s := httptest.NewServer(chi.Chain(http_metrics.Middleware(r), http_compression.Middleware()).Handler(handler(t, 200, "req-body", content)))
+	defer s.Close()
+	req, _ := http.NewRequest("POST", s.URL, bytes.NewBufferString("req-body"))
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	wire, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.Equal(t, "gzip", resp.Header.Get("Content-Encoding"))
+	assert.Equal(t, len(content), r.respsize, "the decoded size should be reported as the response size")
+	assert.Equal(t, len(wire), r.encodedsize, "the compressed size should be reported as the encoded size")
+}
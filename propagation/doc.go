@@ -0,0 +1,16 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_propagation` forwards headers of inbound requests to the outbound requests made while handling them.
+
+Go HTTP servers often make HTTP requests themselves when handling an inbound request, and some values (tracing
+baggage, auth tokens, feature flags) need to be passed from the input to the output. The Middleware captures an
+allow-listed set of inbound headers, given by exact names (e.g. `Authorization`) or prefixes (e.g. `X-B3-`,
+`Baggage-`), into the request's context. The Tripperware sets them on outbound requests made with that context.
+
+The Tripperware only forwards the captured headers that are allowed for all services, or for the service of the
+request, using the service name set by `http_ctxtags.Tripperware`. Nothing is forwarded by default. This makes sure
+that, for example, auth tokens are only forwarded to the internal services they are allowed for.
+*/
+package http_propagation
@@ -0,0 +1,137 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_propagation_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/propagation"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// forwardingHandler makes an outbound request to the service named in the query, and returns its headers as JSON.
+type forwardingHandler struct {
+	tripperware httpwares.Tripperware
+}
+
+func (h *forwardingHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	var outbound *http.Request
+	transport := httpwares.WrapClient(
+		&http.Client{Transport: httpwares.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			outbound = r
+			return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("")), Request: r}, nil
+		})},
+		http_ctxtags.Tripperware(http_ctxtags.WithServiceName(req.URL.Query().Get("service"))),
+		h.tripperware,
+	)
+	outReq, _ := http.NewRequest("GET", "http://upstream.local/", nil)
+	outReq.Header.Set("X-B3-Sampled", "explicit")
+	if _, err := transport.Do(outReq.WithContext(req.Context())); err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if len(outReq.Header) != 1 {
+		resp.WriteHeader(http.StatusInternalServerError) // the tripperware must not modify the original request
+		return
+	}
+	resp.Header().Set("content-type", "application/json")
+	resp.WriteHeader(http.StatusOK)
+	json.NewEncoder(resp).Encode(outbound.Header)
+}
+
+func TestPropagationSuite(t *testing.T) {
+	s := &PropagationSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: &forwardingHandler{
+				tripperware: http_propagation.Tripperware(
+					http_propagation.WithHeaderPrefixes("x-b3-"),
+					http_propagation.WithServiceHeaders("internal", "authorization"),
+					http_propagation.WithServiceHeaderPrefixes("internal", "baggage-"),
+				),
+			},
+			ServerMiddleware: []httpwares.Middleware{
+				http_propagation.Middleware(
+					http_propagation.WithHeaders("authorization"),
+					http_propagation.WithHeaderPrefixes("X-B3-", "Baggage-"),
+				),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type PropagationSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *PropagationSuite) forwardedHeaders(service string) http.Header {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl?service="+service, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("X-B3-TraceId", "463ac35c9f6413ad")
+	req.Header.Set("X-B3-Sampled", "1")
+	req.Header.Set("Baggage-User", "bob")
+	req.Header.Set("X-Other", "not forwarded")
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	require.Equal(s.T(), http.StatusOK, resp.StatusCode, "handler should succeed")
+	headers := http.Header{}
+	require.NoError(s.T(), json.NewDecoder(resp.Body).Decode(&headers), "response should be the outbound headers")
+	return headers
+}
+
+func (s *PropagationSuite) TestAllowListedHeadersAreForwarded() {
+	headers := s.forwardedHeaders("internal")
+	assert.Equal(s.T(), "Bearer secret", headers.Get("Authorization"), "exact names should be forwarded")
+	assert.Equal(s.T(), "463ac35c9f6413ad", headers.Get("X-B3-TraceId"), "prefixed headers should be forwarded")
+	assert.Equal(s.T(), "bob", headers.Get("Baggage-User"), "prefixed headers should be forwarded")
+	assert.Empty(s.T(), headers.Get("X-Other"), "headers not on the allow-list must not be forwarded")
+}
+
+func (s *PropagationSuite) TestOutboundHeadersAreNotOverwritten() {
+	headers := s.forwardedHeaders("internal")
+	assert.Equal(s.T(), "explicit", headers.Get("X-B3-Sampled"), "headers set on the outbound request should be kept")
+}
+
+func (s *PropagationSuite) TestUnlistedServicesOnlyGetGlobalHeaders() {
+	headers := s.forwardedHeaders("external")
+	assert.Equal(s.T(), "463ac35c9f6413ad", headers.Get("X-B3-TraceId"), "headers allowed for all services should be forwarded")
+	assert.Empty(s.T(), headers.Get("Authorization"), "headers only allowed for other services must not be forwarded")
+	assert.Empty(s.T(), headers.Get("Baggage-User"), "headers only allowed for other services must not be forwarded")
+}
+
+func TestTripperwareUsesContextHeaders(t *testing.T) {
+	var seen http.Header
+	transport := http_propagation.Tripperware(http_propagation.WithHeaders("X-Tenant"))(httpwares.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		seen = r.Header
+		return &http.Response{StatusCode: http.StatusOK, Request: r}, nil
+	}))
+	req, _ := http.NewRequest("GET", "http://upstream.local/", nil)
+	ctx := http_propagation.ToContext(req.Context(), http.Header{"X-Tenant": []string{"acme"}})
+	_, err := transport.RoundTrip(req.WithContext(ctx))
+	require.NoError(t, err)
+	assert.Equal(t, "acme", seen.Get("X-Tenant"), "headers put in the context should be propagated")
+}
+
+func TestTripperwareForwardsNothingByDefault(t *testing.T) {
+	var seen http.Header
+	transport := http_propagation.Tripperware()(httpwares.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		seen = r.Header
+		return &http.Response{StatusCode: http.StatusOK, Request: r}, nil
+	}))
+	req, _ := http.NewRequest("GET", "http://upstream.local/", nil)
+	ctx := http_propagation.ToContext(req.Context(), http.Header{"Authorization": []string{"Bearer secret"}})
+	_, err := transport.RoundTrip(req.WithContext(ctx))
+	require.NoError(t, err)
+	assert.Empty(t, seen.Get("Authorization"), "headers not on any allow-list must not be propagated")
+}
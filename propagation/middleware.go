@@ -0,0 +1,35 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_propagation
+
+import (
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+)
+
+// Middleware returns a http.Handler middleware that captures inbound headers for propagation.
+//
+// Only the headers allowed by `WithHeaders` and `WithHeaderPrefixes` are captured, by default none are.
+func Middleware(opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			var captured http.Header
+			for name, values := range req.Header {
+				if !o.allowList.allows(name) {
+					continue
+				}
+				if captured == nil {
+					captured = make(http.Header)
+				}
+				captured[name] = append([]string(nil), values...)
+			}
+			if captured != nil {
+				req = req.WithContext(ToContext(req.Context(), captured))
+			}
+			next.ServeHTTP(resp, req)
+		})
+	}
+}
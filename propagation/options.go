@@ -0,0 +1,114 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_propagation
+
+import (
+	"net/http"
+	"strings"
+)
+
+var (
+	defaultOptions = &options{
+		serviceAllowLists: make(map[string]*allowList),
+	}
+)
+
+type options struct {
+	allowList         allowList
+	serviceAllowLists map[string]*allowList
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.serviceAllowLists = make(map[string]*allowList)
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithHeaders adds headers, by their exact names, to the ones that are propagated.
+func WithHeaders(names ...string) Option {
+	return func(o *options) {
+		o.allowList.addNames(names)
+	}
+}
+
+// WithHeaderPrefixes adds headers whose names start with one of the prefixes (e.g. `X-B3-`) to the ones that are
+// propagated. Prefixes are matched case-insensitively.
+func WithHeaderPrefixes(prefixes ...string) Option {
+	return func(o *options) {
+		o.allowList.addPrefixes(prefixes)
+	}
+}
+
+// WithServiceHeaders adds headers, by their exact names, to the ones that are propagated to the given service only.
+//
+// This only applies to the Tripperware. Services get the headers on their own allow-list (see also
+// `WithServiceHeaderPrefixes`) on top of the ones allowed by `WithHeaders` and `WithHeaderPrefixes`, so headers such
+// as `Authorization` should only be allowed for the services that need them.
+func WithServiceHeaders(serviceName string, names ...string) Option {
+	return func(o *options) {
+		o.serviceAllowList(serviceName).addNames(names)
+	}
+}
+
+// WithServiceHeaderPrefixes adds headers whose names start with one of the prefixes to the ones that are propagated to
+// the given service only.
+//
+// This only applies to the Tripperware, see `WithServiceHeaders`.
+func WithServiceHeaderPrefixes(serviceName string, prefixes ...string) Option {
+	return func(o *options) {
+		o.serviceAllowList(serviceName).addPrefixes(prefixes)
+	}
+}
+
+func (o *options) serviceAllowList(serviceName string) *allowList {
+	l, ok := o.serviceAllowLists[serviceName]
+	if !ok {
+		l = &allowList{}
+		o.serviceAllowLists[serviceName] = l
+	}
+	return l
+}
+
+// allowList matches header names either exactly or by their prefix.
+type allowList struct {
+	names    []string
+	prefixes []string
+}
+
+func (l *allowList) addNames(names []string) {
+	for _, n := range names {
+		l.names = append(l.names, http.CanonicalHeaderKey(n))
+	}
+}
+
+func (l *allowList) addPrefixes(prefixes []string) {
+	for _, p := range prefixes {
+		l.prefixes = append(l.prefixes, strings.ToLower(p))
+	}
+}
+
+// allows checks whether the header is on the list. The name must be in its canonical form.
+func (l *allowList) allows(name string) bool {
+	for _, n := range l.names {
+		if n == name {
+			return true
+		}
+	}
+	if len(l.prefixes) == 0 {
+		return false
+	}
+	lower := strings.ToLower(name)
+	for _, p := range l.prefixes {
+		if strings.HasPrefix(lower, p) {
+			return true
+		}
+	}
+	return false
+}
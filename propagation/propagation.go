@@ -0,0 +1,30 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_propagation
+
+import (
+	"context"
+	"net/http"
+)
+
+type ctxMarker struct{}
+
+var (
+	ctxHeaders = &ctxMarker{}
+)
+
+// Extract returns the headers captured for propagation in the context, or nil if there are none.
+//
+// The returned headers must not be modified.
+func Extract(ctx context.Context) http.Header {
+	h, _ := ctx.Value(ctxHeaders).(http.Header)
+	return h
+}
+
+// ToContext returns a context holding the given headers for propagation by the Tripperware.
+//
+// This is useful for requests made outside of the Middleware, e.g. in background jobs started by a handler.
+func ToContext(ctx context.Context, headers http.Header) context.Context {
+	return context.WithValue(ctx, ctxHeaders, headers)
+}
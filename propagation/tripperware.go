@@ -0,0 +1,61 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_propagation
+
+import (
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/internal/header"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// Tripperware returns a new client-side ware that sets the captured headers on outbound requests.
+//
+// The headers are taken from the context of the request (see `Extract`), so outbound requests need to be made with the
+// context of the inbound request. Headers already set on the outbound request are left alone.
+//
+// Only the captured headers allowed by `WithHeaders` and `WithHeaderPrefixes`, or by the allow-list of the service
+// set with `WithServiceHeaders` and `WithServiceHeaderPrefixes`, are propagated, by default none are. Services are
+// named by the `http_ctxtags.Tripperware`, which should be placed before this one in the chain. Requests without a
+// service tag use the `http_ctxtags.DefaultServiceNameDetector`.
+func Tripperware(opts ...Option) httpwares.Tripperware {
+	o := evaluateOptions(opts)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			captured := Extract(req.Context())
+			if len(captured) == 0 {
+				return next.RoundTrip(req)
+			}
+			var serviceAllowed *allowList
+			if len(o.serviceAllowLists) > 0 {
+				serviceAllowed = o.serviceAllowLists[serviceName(req)]
+			}
+			var newReq *http.Request
+			for name, values := range captured {
+				if _, ok := req.Header[name]; ok {
+					continue
+				}
+				if !o.allowList.allows(name) && (serviceAllowed == nil || !serviceAllowed.allows(name)) {
+					continue
+				}
+				if newReq == nil {
+					newReq = http_header.CloneRequestWithHeader(req)
+				}
+				newReq.Header[name] = values
+			}
+			if newReq == nil {
+				return next.RoundTrip(req)
+			}
+			return next.RoundTrip(newReq)
+		})
+	}
+}
+
+func serviceName(req *http.Request) string {
+	if svc, ok := http_ctxtags.ExtractOutbound(req).Values()[http_ctxtags.TagForCallService].(string); ok {
+		return svc
+	}
+	return http_ctxtags.DefaultServiceNameDetector(req)
+}
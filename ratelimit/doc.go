@@ -0,0 +1,23 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_ratelimit` limits the rate of HTTP requests using token buckets.
+
+Client-side Rate Limiting
+
+The Tripperware keeps a token bucket for each service called, as identified by the `http.call.service` tag of
+`http_ctxtags`, or for each key returned by a custom `KeyFunc`. Each request takes a token from its bucket. If none is
+available, the request either waits for one (as long as it can be taken before the deadline of the request's context)
+or fails immediately with a `*LimitExceededError` if `WithFailFast` is used.
+
+Server-side Rate Limiting
+
+The Middleware keeps a token bucket for each peer address, or for each key returned by a custom `KeyFunc` (e.g.
+`HeaderKey("X-Api-Key")`). Requests that find their bucket empty are rejected with a 429 status code and a
+`Retry-After` header. Limits can be set per handler group of `http_ctxtags` with `WithGroupLimit`.
+
+The buckets are kept in a `Store`, which by default is in memory. Other implementations can share the limits
+between multiple servers.
+*/
+package http_ratelimit
@@ -0,0 +1,165 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_ratelimit_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/ratelimit"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+var (
+	rateInterval = 100 * time.Millisecond
+	clientLimit  = http_ratelimit.Limit{Rate: float64(time.Second / rateInterval), Burst: 1}
+)
+
+func TestRateLimitTripperwareSuite(t *testing.T) {
+	s := &RateLimitTripperwareSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: httpwares_testing.PingBackHandler(httpwares_testing.DefaultPingBackStatusCode),
+		},
+	}
+	suite.Run(t, s)
+}
+
+type RateLimitTripperwareSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *RateLimitTripperwareSuite) newClient(opts ...http_ratelimit.Option) *http.Client {
+	s.ClientTripperware = []httpwares.Tripperware{
+		http_ctxtags.Tripperware(http_ctxtags.WithServiceNameDetector(func(req *http.Request) string {
+			return req.URL.Query().Get("service")
+		})),
+		http_ratelimit.Tripperware(clientLimit, opts...),
+	}
+	return s.NewClient()
+}
+
+func (s *RateLimitTripperwareSuite) call(client *http.Client, ctx context.Context, service string) error {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl?service="+service, nil)
+	resp, err := client.Do(req.WithContext(ctx))
+	if err == nil {
+		resp.Body.Close()
+	}
+	return err
+}
+
+func (s *RateLimitTripperwareSuite) TestWaitsForToken() {
+	client := s.newClient()
+	start := time.Now()
+	require.NoError(s.T(), s.call(client, s.SimpleCtx(), "svc"), "first call should use the burst")
+	require.NoError(s.T(), s.call(client, s.SimpleCtx(), "other"), "other services should have their own bucket")
+	require.NoError(s.T(), s.call(client, s.SimpleCtx(), "svc"), "second call should wait for a token")
+	assert.True(s.T(), time.Since(start) >= rateInterval*9/10, "second call should have waited for the rate")
+}
+
+func (s *RateLimitTripperwareSuite) TestFailsFast() {
+	client := s.newClient(http_ratelimit.WithFailFast())
+	require.NoError(s.T(), s.call(client, s.SimpleCtx(), "svc"), "first call should use the burst")
+	err := s.call(client, s.SimpleCtx(), "svc")
+	require.Error(s.T(), err, "second call should fail")
+	limitErr, ok := err.(*url.Error).Err.(*http_ratelimit.LimitExceededError)
+	require.True(s.T(), ok, "the error should be a LimitExceededError, got %v", err)
+	assert.Equal(s.T(), "svc", limitErr.Key, "the error should name the bucket")
+	assert.True(s.T(), limitErr.RetryAfter > 0 && limitErr.RetryAfter <= rateInterval, "the error should say when to retry")
+}
+
+func (s *RateLimitTripperwareSuite) TestFailsIfTokenPastDeadline() {
+	client := s.newClient()
+	require.NoError(s.T(), s.call(client, s.SimpleCtx(), "svc"), "first call should use the burst")
+	ctx, cancel := context.WithTimeout(s.SimpleCtx(), rateInterval/4)
+	defer cancel()
+	start := time.Now()
+	err := s.call(client, ctx, "svc")
+	require.Error(s.T(), err, "second call should fail")
+	_, ok := err.(*url.Error).Err.(*http_ratelimit.LimitExceededError)
+	assert.True(s.T(), ok, "the error should be a LimitExceededError, got %v", err)
+	assert.True(s.T(), time.Since(start) < rateInterval/4, "the call shouldn't wait for the deadline")
+}
+
+func (s *RateLimitTripperwareSuite) TestKeyLimitOverride() {
+	client := s.newClient(http_ratelimit.WithFailFast(), http_ratelimit.WithKeyLimit("svc", http_ratelimit.Limit{Rate: 1, Burst: 3}))
+	for i := 0; i < 3; i++ {
+		require.NoError(s.T(), s.call(client, s.SimpleCtx(), "svc"), "call %d should use the overridden burst", i)
+	}
+	require.Error(s.T(), s.call(client, s.SimpleCtx(), "svc"), "the overridden burst should be used up")
+}
+
+func TestRateLimitMiddlewareSuite(t *testing.T) {
+	limiter := http_ratelimit.Middleware(
+		http_ratelimit.Limit{Rate: 0.01, Burst: 2},
+		http_ratelimit.WithKeyFunc(http_ratelimit.HeaderKey("X-Api-Key")),
+		http_ratelimit.WithGroupLimit("strict", http_ratelimit.Limit{Rate: 0.01, Burst: 1}),
+		http_ratelimit.WithKeyLimit("header:vip", http_ratelimit.Limit{Rate: 0.01, Burst: 5}),
+	)
+	pingBack := httpwares_testing.PingBackHandler(httpwares_testing.DefaultPingBackStatusCode)
+	mux := http.NewServeMux()
+	mux.Handle("/default", http_ctxtags.Middleware("default")(limiter(pingBack)))
+	mux.Handle("/strict", http_ctxtags.Middleware("strict")(limiter(pingBack)))
+	s := &RateLimitMiddlewareSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: mux,
+		},
+	}
+	suite.Run(t, s)
+}
+
+type RateLimitMiddlewareSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *RateLimitMiddlewareSuite) call(path string, apiKey string) *http.Response {
+	req, _ := http.NewRequest("GET", "https://something.local"+path, nil)
+	req.Header.Set("X-Api-Key", apiKey)
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	resp.Body.Close()
+	return resp
+}
+
+func (s *RateLimitMiddlewareSuite) TestRejectsOverLimit() {
+	resp := s.call("/default", "alice")
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "first call should pass")
+	assert.Equal(s.T(), "2", resp.Header.Get("X-RateLimit-Limit"), "limit should be reported")
+	assert.Equal(s.T(), "1", resp.Header.Get("X-RateLimit-Remaining"), "remaining tokens should be reported")
+	assert.Equal(s.T(), "100", resp.Header.Get("X-RateLimit-Reset"), "time to refill should be reported")
+	resp = s.call("/default", "alice")
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "second call should use the burst")
+	resp = s.call("/default", "alice")
+	assert.Equal(s.T(), http.StatusTooManyRequests, resp.StatusCode, "third call should be rejected")
+	assert.Equal(s.T(), "0", resp.Header.Get("X-RateLimit-Remaining"), "remaining tokens should be reported")
+	assert.Equal(s.T(), "100", resp.Header.Get("Retry-After"), "the time to the next token should be reported")
+	resp = s.call("/default", "bob")
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "other keys should have their own buckets")
+}
+
+func (s *RateLimitMiddlewareSuite) TestGroupLimit() {
+	resp := s.call("/strict", "carol")
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "first call should pass")
+	assert.Equal(s.T(), "1", resp.Header.Get("X-RateLimit-Limit"), "group limit should be reported")
+	resp = s.call("/strict", "carol")
+	assert.Equal(s.T(), http.StatusTooManyRequests, resp.StatusCode, "group limit should apply")
+	resp = s.call("/default", "carol")
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "other groups should have their own buckets")
+}
+
+func (s *RateLimitMiddlewareSuite) TestKeyLimit() {
+	for i := 0; i < 5; i++ {
+		resp := s.call("/strict", "vip")
+		assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "call %d should use the key's burst", i)
+	}
+	resp := s.call("/strict", "vip")
+	assert.Equal(s.T(), http.StatusTooManyRequests, resp.StatusCode, "key limit should apply")
+}
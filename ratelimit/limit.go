@@ -0,0 +1,121 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Limit describes a token bucket.
+type Limit struct {
+	// Rate is the number of tokens added to the bucket each second, i.e. the sustained rate of requests.
+	Rate float64
+	// Burst is the maximum number of tokens in the bucket, i.e. the number of requests that can be made at once.
+	Burst int
+}
+
+// LimitExceededError is returned for requests that exceeded the rate limit.
+type LimitExceededError struct {
+	// Key identifies the bucket that ran out of tokens.
+	Key string
+	// RetryAfter is the time after which a token will be available.
+	RetryAfter time.Duration
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %q, retry after %v", e.Key, e.RetryAfter)
+}
+
+// bucket is a token bucket that allows taking tokens in advance, making the following requests wait longer.
+type bucket struct {
+	mu     sync.Mutex
+	limit  Limit
+	tokens float64
+	last   time.Time
+	// pending counts the takes from a `memoryStore` that found the bucket but haven't taken a token from it yet.
+	pending int32
+}
+
+func newBucket(limit Limit, now time.Time) *bucket {
+	return &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+}
+
+// take takes a token that becomes available within maxWait, returning how long to wait for it.
+//
+// If the token won't be available within maxWait, nothing is taken and false is returned along with the wait needed.
+func (b *bucket) take(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	wait := b.waitFor(b.tokens - 1)
+	if wait > maxWait {
+		return wait, false
+	}
+	b.tokens--
+	return wait, true
+}
+
+// takeNow takes a token if one is available right away, updating the limit of the bucket.
+func (b *bucket) takeNow(now time.Time, limit Limit) *Result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit = limit
+	b.refill(now)
+	result := &Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	}
+	result.Remaining = int(b.tokens)
+	result.RetryAfter = b.waitFor(b.tokens - 1)
+	result.Reset = b.waitFor(b.tokens - float64(b.limit.Burst))
+	return result
+}
+
+func (b *bucket) isFull(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	return b.tokens >= float64(b.limit.Burst)
+}
+
+// giveBack returns a token taken in advance that wasn't used.
+func (b *bucket) giveBack(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(now)
+	b.tokens++
+	if b.tokens > float64(b.limit.Burst) {
+		b.tokens = float64(b.limit.Burst)
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.limit.Rate
+		if b.tokens > float64(b.limit.Burst) {
+			b.tokens = float64(b.limit.Burst)
+		}
+		b.last = now
+	}
+}
+
+// waitFor returns how long it takes for the bucket to go from the given number of tokens back to none.
+func (b *bucket) waitFor(tokens float64) time.Duration {
+	if tokens >= 0 {
+		return 0
+	}
+	if b.limit.Rate <= 0 {
+		return maxDuration
+	}
+	seconds := -tokens / b.limit.Rate
+	if seconds >= maxDuration.Seconds() {
+		return maxDuration
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+const maxDuration = time.Duration(1<<63 - 1)
@@ -0,0 +1,57 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketAllowsBurstThenRate(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newBucket(Limit{Rate: 10, Burst: 2}, now)
+	for i := 0; i < 2; i++ {
+		wait, ok := b.take(now, 0)
+		assert.True(t, ok, "burst token %d should be available", i)
+		assert.Equal(t, time.Duration(0), wait, "burst token %d shouldn't need a wait", i)
+	}
+	wait, ok := b.take(now, 0)
+	assert.False(t, ok, "no tokens should be left")
+	assert.Equal(t, 100*time.Millisecond, wait, "the next token should be available after 1/rate")
+	wait, ok = b.take(now, time.Second)
+	assert.True(t, ok, "tokens can be taken in advance")
+	assert.Equal(t, 100*time.Millisecond, wait, "the next token should be available after 1/rate")
+	wait, ok = b.take(now, time.Second)
+	assert.True(t, ok, "tokens can be taken in advance")
+	assert.Equal(t, 200*time.Millisecond, wait, "tokens taken in advance should make the next request wait longer")
+	wait, _ = b.take(now.Add(200*time.Millisecond), 0)
+	assert.Equal(t, 100*time.Millisecond, wait, "the bucket should refill over time")
+}
+
+func TestBucketGiveBack(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newBucket(Limit{Rate: 1, Burst: 1}, now)
+	b.take(now, 0)
+	_, ok := b.take(now, 0)
+	assert.False(t, ok, "no tokens should be left")
+	b.giveBack(now)
+	_, ok = b.take(now, 0)
+	assert.True(t, ok, "the token given back should be available")
+	b.giveBack(now)
+	b.giveBack(now)
+	b.take(now, 0)
+	_, ok = b.take(now, 0)
+	assert.False(t, ok, "giving back shouldn't exceed the burst")
+}
+
+func TestBucketWithZeroRate(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newBucket(Limit{Rate: 0, Burst: 1}, now)
+	b.take(now, 0)
+	wait, ok := b.take(now.Add(time.Hour), time.Hour)
+	assert.False(t, ok, "a bucket with no rate shouldn't refill")
+	assert.Equal(t, maxDuration, wait, "the wait should be infinite")
+}
@@ -0,0 +1,68 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// Middleware returns a http.Handler middleware that limits the rate of requests handled.
+//
+// Each key (by default the peer address) gets its own token bucket with the given limit, unless overridden using
+// `WithGroupLimit` or `WithKeyLimit`. Requests for which no token is available are rejected with a 429 (Too Many
+// Requests) status code and a `Retry-After` header. All responses carry the `X-RateLimit-Limit`,
+// `X-RateLimit-Remaining` and `X-RateLimit-Reset` (in seconds) headers.
+//
+// Group limits need `http_ctxtags.Middleware` to be placed before this one in the chain. If the Store fails, the
+// request is let through.
+func Middleware(limit Limit, opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	if o.keyFunc == nil {
+		o.keyFunc = PeerAddressKey
+	}
+	if o.store == nil {
+		o.store = NewMemoryStore()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			key := o.keyFunc(req)
+			bucketKey, keyLimit := key, limit
+			if group, ok := http_ctxtags.ExtractInbound(req).Values()[http_ctxtags.TagForHandlerGroup].(string); ok {
+				if groupLimit, ok := o.groupLimits[group]; ok {
+					bucketKey, keyLimit = group+"/"+key, groupLimit
+				}
+			}
+			if overridden, ok := o.keyLimits[key]; ok {
+				keyLimit = overridden
+			}
+			result, err := o.store.Take(bucketKey, keyLimit)
+			if err != nil {
+				next.ServeHTTP(resp, req)
+				return
+			}
+			resp.Header().Set("X-RateLimit-Limit", strconv.Itoa(keyLimit.Burst))
+			resp.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			resp.Header().Set("X-RateLimit-Reset", ceilSeconds(result.Reset))
+			if !result.Allowed {
+				resp.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				http.Error(resp, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(resp, req)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) string {
+	seconds := int64(d / time.Second)
+	if d%time.Second > 0 {
+		seconds++
+	}
+	return strconv.FormatInt(seconds, 10)
+}
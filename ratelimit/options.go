@@ -0,0 +1,135 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+var (
+	defaultOptions = &options{
+		keyFunc:     nil,
+		keyLimits:   map[string]Limit{},
+		groupLimits: map[string]Limit{},
+		failFast:    false,
+		store:       nil,
+	}
+)
+
+type options struct {
+	keyFunc     KeyFunc
+	keyLimits   map[string]Limit
+	groupLimits map[string]Limit
+	failFast    bool
+	store       Store
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.keyLimits = make(map[string]Limit)
+	optCopy.groupLimits = make(map[string]Limit)
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// KeyFunc returns the key of the bucket the request takes its token from.
+type KeyFunc func(req *http.Request) string
+
+// WithKeyFunc customizes how requests are assigned to buckets.
+//
+// By default the Tripperware uses the `ServiceNameKey` and the Middleware uses the `PeerAddressKey`.
+func WithKeyFunc(f KeyFunc) Option {
+	return func(o *options) {
+		o.keyFunc = f
+	}
+}
+
+// WithKeyLimit overrides the limit for the bucket with the given key, e.g. the name of a service.
+func WithKeyLimit(key string, limit Limit) Option {
+	return func(o *options) {
+		o.keyLimits[key] = limit
+	}
+}
+
+// WithGroupLimit sets the limit for requests to handlers of the given group, as set by `http_ctxtags.Middleware`.
+//
+// Each group with its own limit keeps separate buckets, so a client limited in one group can still call the others.
+// This option only applies to the Middleware, and `WithKeyLimit` takes precedence over it.
+func WithGroupLimit(handlerGroup string, limit Limit) Option {
+	return func(o *options) {
+		o.groupLimits[handlerGroup] = limit
+	}
+}
+
+// WithStore sets the Store used by the Middleware to keep its buckets.
+//
+// By default each Middleware uses its own `NewMemoryStore`.
+func WithStore(store Store) Option {
+	return func(o *options) {
+		o.store = store
+	}
+}
+
+// WithFailFast makes the Tripperware fail requests with a `*LimitExceededError` straight away if no token is available,
+// instead of waiting for one.
+//
+// This option only applies to the Tripperware, as the Middleware never waits.
+func WithFailFast() Option {
+	return func(o *options) {
+		o.failFast = true
+	}
+}
+
+// ServiceNameKey is a KeyFunc that uses the `http_ctxtags.TagForCallService` of the request.
+//
+// If the tag is not set, the `http_ctxtags.DefaultServiceNameDetector` is used.
+func ServiceNameKey(req *http.Request) string {
+	if svc, ok := http_ctxtags.ExtractOutbound(req).Values()[http_ctxtags.TagForCallService].(string); ok {
+		return svc
+	}
+	return http_ctxtags.DefaultServiceNameDetector(req)
+}
+
+// PeerAddressKey is a KeyFunc that uses the "peer.address" tag set by `http_ctxtags.Middleware`.
+//
+// If the tag is not set, the host of `http.Request.RemoteAddr` is used.
+func PeerAddressKey(req *http.Request) string {
+	if addr, ok := http_ctxtags.ExtractInbound(req).Values()["peer.address"].(string); ok {
+		return addr
+	}
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
+
+// HeaderKey returns a KeyFunc that uses the value of the given header, e.g. an API key.
+//
+// Requests without the header fall back to the `PeerAddressKey`, so that anonymous clients don't throttle each other.
+// The keys are prefixed with "header:" and "addr:" respectively, so that a client can't use up the tokens of a peer by
+// sending its address in the header. Limits set with `WithKeyLimit` need the prefix too, e.g. "header:my-api-key".
+func HeaderKey(header string) KeyFunc {
+	return func(req *http.Request) string {
+		if value := req.Header.Get(header); value != "" {
+			return "header:" + value
+		}
+		return "addr:" + PeerAddressKey(req)
+	}
+}
+
+// HandlerKey is a KeyFunc that uses the handler group and name tags set by `http_ctxtags`, limiting the total rate of
+// requests to each handler.
+func HandlerKey(req *http.Request) string {
+	values := http_ctxtags.ExtractInbound(req).Values()
+	return fmt.Sprintf("%v.%v", values[http_ctxtags.TagForHandlerGroup], values[http_ctxtags.TagForHandlerName])
+}
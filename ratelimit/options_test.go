@@ -0,0 +1,39 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_ratelimit
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderKeyFallsBackToPeerAddress(t *testing.T) {
+	keyFunc := HeaderKey("X-Api-Key")
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	assert.Equal(t, "addr:10.0.0.1", keyFunc(req), "requests without the header should be keyed by peer address")
+	req.Header.Set("X-Api-Key", "alice")
+	assert.Equal(t, "header:alice", keyFunc(req), "requests with the header should be keyed by its value")
+}
+
+func TestHeaderKeySpoofingPeerAddressDoesNotUseItsTokens(t *testing.T) {
+	keyFunc := HeaderKey("X-Api-Key")
+	store := NewMemoryStore()
+	limit := Limit{Rate: 0.01, Burst: 1}
+	spoofed, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	spoofed.RemoteAddr = "10.0.0.2:1234"
+	spoofed.Header.Set("X-Api-Key", "10.0.0.1")
+	result, err := store.Take(keyFunc(spoofed), limit)
+	require.NoError(t, err, "take shouldn't fail")
+	assert.True(t, result.Allowed, "the spoofed request should take from its own bucket")
+
+	victim, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	victim.RemoteAddr = "10.0.0.1:1234"
+	result, err = store.Take(keyFunc(victim), limit)
+	require.NoError(t, err, "take shouldn't fail")
+	assert.True(t, result.Allowed, "the tokens of the peer shouldn't be used up by a header with its address")
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_ratelimit
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const memoryStoreSweepInterval = 1024
+
+// Store keeps the token buckets of the Middleware.
+//
+// Implementations can keep the buckets outside of the process (e.g. in Redis) to share the limits between servers.
+type Store interface {
+	// Take takes a token from the bucket with the given key, creating it with the given limit if needed.
+	Take(key string, limit Limit) (*Result, error)
+}
+
+// Result is the state of a bucket after taking a token from it.
+type Result struct {
+	// Allowed is true if a token was available.
+	Allowed bool
+	// Remaining is the number of tokens left in the bucket.
+	Remaining int
+	// RetryAfter is the time after which the next token becomes available.
+	RetryAfter time.Duration
+	// Reset is the time after which the bucket is full again.
+	Reset time.Duration
+}
+
+// NewMemoryStore returns a Store that keeps the buckets in memory.
+//
+// Buckets that are full are removed from time to time, so the memory used is proportional to the number of keys seen
+// recently.
+func NewMemoryStore() Store {
+	return &memoryStore{buckets: make(map[string]*bucket), clock: time.Now}
+}
+
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   uint
+	clock   func() time.Time
+}
+
+func (s *memoryStore) Take(key string, limit Limit) (*Result, error) {
+	now := s.clock()
+	s.mu.Lock()
+	s.takes++
+	if s.takes%memoryStoreSweepInterval == 0 {
+		s.sweep(now)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		b = newBucket(limit, now)
+		s.buckets[key] = b
+	}
+	atomic.AddInt32(&b.pending, 1) // keeps the sweep from removing the bucket before the token is taken
+	s.mu.Unlock()
+	result := b.takeNow(now, limit)
+	atomic.AddInt32(&b.pending, -1)
+	return result, nil
+}
+
+// sweep removes the buckets that are full, as they are no different from new ones.
+//
+// Buckets that a token is about to be taken from are kept, as removing them would lose the token taken.
+func (s *memoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if atomic.LoadInt32(&b.pending) == 0 && b.isFull(now) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStoreRemovesFullBuckets(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore().(*memoryStore)
+	s.clock = func() time.Time { return now }
+	result, err := s.Take("slow", Limit{Rate: 0.1, Burst: 1})
+	require.NoError(t, err, "take shouldn't fail")
+	assert.True(t, result.Allowed, "first take should be allowed")
+	assert.Equal(t, 10*time.Second, result.Reset, "bucket should be full after ten seconds")
+	for i := 2; i < memoryStoreSweepInterval; i++ {
+		s.Take("fast", Limit{Rate: 1000, Burst: 1})
+	}
+	now = now.Add(time.Second)
+	result, _ = s.Take("slow", Limit{Rate: 0.1, Burst: 1}) // triggers the sweep
+	assert.False(t, result.Allowed, "bucket shouldn't refill in under ten seconds")
+	assert.Equal(t, 9*time.Second, result.RetryAfter, "next token should be available after ten seconds")
+	assert.Len(t, s.buckets, 1, "only the full bucket should be removed")
+	assert.Contains(t, s.buckets, "slow", "buckets that aren't full should be kept")
+}
+
+func TestMemoryStoreKeepsBucketsBeingTakenFrom(t *testing.T) {
+	now := time.Unix(1000, 0)
+	s := NewMemoryStore().(*memoryStore)
+	s.clock = func() time.Time { return now }
+	s.buckets["busy"] = newBucket(Limit{Rate: 1, Burst: 1}, now)
+	s.buckets["busy"].pending = 1 // a take found the bucket, but hasn't taken its token yet
+	s.buckets["idle"] = newBucket(Limit{Rate: 1, Burst: 1}, now)
+	s.sweep(now)
+	assert.Contains(t, s.buckets, "busy", "buckets with pending takes must be kept")
+	assert.NotContains(t, s.buckets, "idle", "full buckets without pending takes should be removed")
+}
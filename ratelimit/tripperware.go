@@ -0,0 +1,77 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_ratelimit
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+)
+
+// Tripperware is client side HTTP ware that limits the rate of requests made.
+//
+// Each key (by default the service name) gets its own token bucket with the given limit, unless overridden using
+// `WithKeyLimit`. The buckets are shared by all the transports wrapped with the returned Tripperware.
+//
+// Requests that can't get a token straight away wait for one, unless `WithFailFast` is used. If the token wouldn't be
+// available before the deadline of the request's context, the request fails with a `*LimitExceededError` immediately.
+func Tripperware(limit Limit, opts ...Option) httpwares.Tripperware {
+	o := evaluateOptions(opts)
+	if o.keyFunc == nil {
+		o.keyFunc = ServiceNameKey
+	}
+	buckets := &bucketSet{limit: limit, opts: o, buckets: make(map[string]*bucket)}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			key := o.keyFunc(req)
+			b := buckets.get(key)
+			now := time.Now()
+			maxWait := maxDuration
+			if o.failFast {
+				maxWait = 0
+			} else if deadline, ok := req.Context().Deadline(); ok {
+				maxWait = deadline.Sub(now)
+			}
+			wait, ok := b.take(now, maxWait)
+			if !ok {
+				return nil, &LimitExceededError{Key: key, RetryAfter: wait}
+			}
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-req.Context().Done():
+					timer.Stop()
+					b.giveBack(time.Now())
+					return nil, req.Context().Err()
+				case <-timer.C:
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+type bucketSet struct {
+	mu      sync.Mutex
+	limit   Limit
+	opts    *options
+	buckets map[string]*bucket
+}
+
+func (s *bucketSet) get(key string) *bucket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.buckets[key]
+	if !ok {
+		limit, ok := s.opts.keyLimits[key]
+		if !ok {
+			limit = s.limit
+		}
+		b = newBucket(limit, time.Now())
+		s.buckets[key] = b
+	}
+	return b
+}
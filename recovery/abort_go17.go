@@ -0,0 +1,11 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// +build go1.7,!go1.8
+
+package http_recovery
+
+// http.ErrAbortHandler was only introduced in Go 1.8.
+func isAbortHandler(p interface{}) bool {
+	return false
+}
@@ -0,0 +1,12 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// +build go1.8
+
+package http_recovery
+
+import "net/http"
+
+func isAbortHandler(p interface{}) bool {
+	return p == http.ErrAbortHandler
+}
@@ -0,0 +1,17 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_recovery` is a HTTP server-side Middleware that recovers from panics in handlers.
+
+A panic is recorded as the `http.panic` and `http.panic.stack` fields of the request's `ctxlogrus` logger, so that it is
+part of the log statement of `http_logrus.Middleware`, and the request's opentracing span is marked as errored. Then the
+`RecoveryHandlerFunc` is called, which by default responds with a 500 (Internal Server Error) status code, unless the
+headers of the response were already sent.
+
+This Middleware should be placed after the logging and tracing middleware in the chain, so that they see the request
+finish normally.
+
+Panics with `http.ErrAbortHandler` are not recovered from, as they are used to abort the response on purpose.
+*/
+package http_recovery
@@ -0,0 +1,8 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// +build go1.7,!go1.8
+
+package http_recovery_test
+
+func panicWithAbortHandler() {}
@@ -0,0 +1,22 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// +build go1.8
+
+package http_recovery_test
+
+import (
+	"net/http"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func panicWithAbortHandler() {
+	panic(http.ErrAbortHandler)
+}
+
+func (s *RecoverySuite) TestAbortHandlerIsNotRecovered() {
+	_, err := s.call("abort")
+	assert.Error(s.T(), err, "the response should be aborted")
+	assert.Empty(s.T(), s.mockTracer.FinishedSpans(), "the panic should go through the other middleware")
+}
@@ -0,0 +1,144 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_recovery_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/logging/logrus"
+	"github.com/improbable-eng/go-httpwares/recovery"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/improbable-eng/go-httpwares/tracing/opentracing"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type panickingHandler struct{}
+
+func (panickingHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	switch req.URL.Query().Get("panic") {
+	case "before_headers":
+		panic("boom before headers")
+	case "after_headers":
+		resp.WriteHeader(http.StatusOK)
+		resp.Write([]byte("partial response"))
+		resp.(http.Flusher).Flush()
+		panic("boom after headers")
+	case "abort":
+		panicWithAbortHandler()
+	}
+	httpwares_testing.PingBackHandler(httpwares_testing.DefaultPingBackStatusCode).ServeHTTP(resp, req)
+}
+
+func TestRecoverySuite(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	log := logrus.New()
+	log.Out = httpwares_testing.NewMutexReadWriter(buffer)
+	log.Formatter = &logrus.JSONFormatter{DisableTimestamp: true}
+	mockTracer := mocktracer.New()
+	s := &RecoverySuite{
+		buffer:     buffer,
+		mockTracer: mockTracer,
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: panickingHandler{},
+			ServerMiddleware: []httpwares.Middleware{
+				http_logrus.Middleware(logrus.NewEntry(log)),
+				http_opentracing.Middleware(http_opentracing.WithTracer(mockTracer)),
+				http_recovery.Middleware(),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type RecoverySuite struct {
+	*httpwares_testing.WaresTestSuite
+	buffer     *bytes.Buffer
+	mockTracer *mocktracer.MockTracer
+}
+
+func (s *RecoverySuite) SetupTest() {
+	s.buffer.Reset()
+	s.mockTracer.Reset()
+}
+
+func (s *RecoverySuite) call(panicMode string) (*http.Response, error) {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl?panic="+panicMode, nil)
+	return s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+}
+
+func (s *RecoverySuite) loggedFields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	lines := strings.Split(strings.TrimSpace(s.buffer.String()), "\n")
+	require.NoError(s.T(), json.Unmarshal([]byte(lines[len(lines)-1]), &fields), "log statement should be JSON")
+	return fields
+}
+
+func (s *RecoverySuite) TestPanicBeforeHeadersReturns500() {
+	resp, err := s.call("before_headers")
+	require.NoError(s.T(), err, "call shouldn't fail")
+	resp.Body.Close()
+	assert.Equal(s.T(), http.StatusInternalServerError, resp.StatusCode, "panic should result in a 500")
+
+	fields := s.loggedFields()
+	assert.Equal(s.T(), "boom before headers", fields["http.panic"], "panic should be logged")
+	assert.Contains(s.T(), fields["http.panic.stack"], "panickingHandler", "stack should be logged")
+	assert.EqualValues(s.T(), http.StatusInternalServerError, fields["http.response.status"], "status should be logged")
+
+	spans := s.mockTracer.FinishedSpans()
+	require.Len(s.T(), spans, 1, "the server span should be finished")
+	assert.Equal(s.T(), true, spans[0].Tag("error"), "the span should be marked as errored")
+	require.NotEmpty(s.T(), spans[0].Logs(), "the panic should be logged in the span")
+}
+
+func (s *RecoverySuite) TestPanicAfterHeadersKeepsResponse() {
+	resp, err := s.call("after_headers")
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode, "the status written before the panic should be kept")
+	body, _ := ioutil.ReadAll(resp.Body)
+	assert.Equal(s.T(), "partial response", string(body), "nothing should be written after the panic")
+
+	fields := s.loggedFields()
+	assert.Equal(s.T(), "boom after headers", fields["http.panic"], "panic should be logged")
+	spans := s.mockTracer.FinishedSpans()
+	require.Len(s.T(), spans, 1, "the server span should be finished")
+	assert.Equal(s.T(), true, spans[0].Tag("error"), "the span should be marked as errored")
+}
+
+func (s *RecoverySuite) TestNoPanic() {
+	resp, err := s.call("")
+	require.NoError(s.T(), err, "call shouldn't fail")
+	resp.Body.Close()
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "response should succeed")
+	assert.NotContains(s.T(), s.loggedFields(), "http.panic", "no panic should be logged")
+}
+
+func TestCustomRecoveryHandler(t *testing.T) {
+	var recovered interface{}
+	handler := http_recovery.Middleware(
+		http_recovery.WithRecoveryHandler(func(resp httpwares.WrappedResponseWriter, req *http.Request, p interface{}) {
+			recovered = p
+			resp.WriteHeader(http.StatusTeapot)
+			resp.Write([]byte("recovered"))
+		}),
+	)(panickingHandler{})
+	req := httptest.NewRequest("GET", "/someurl?panic=before_headers", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+	assert.Equal(t, "boom before headers", recovered, "the recovery handler should get the panic value")
+	assert.Equal(t, http.StatusTeapot, recorder.Code, "the recovery handler should write the response")
+	body, _ := ioutil.ReadAll(recorder.Body)
+	assert.Equal(t, "recovered", string(body), "the recovery handler should write the response")
+}
@@ -0,0 +1,55 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_recovery
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/logging/logrus/ctxlogrus"
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/sirupsen/logrus"
+)
+
+// Middleware returns a http.Handler middleware that recovers from panics in handlers.
+func Middleware(opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			wrapped := httpwares.WrapResponseWriter(resp)
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+				if isAbortHandler(p) {
+					panic(p)
+				}
+				recordPanic(req, p, debug.Stack())
+				o.recoveryHandler(wrapped, req, p)
+			}()
+			next.ServeHTTP(wrapped, req)
+		})
+	}
+}
+
+func recordPanic(req *http.Request, p interface{}, stack []byte) {
+	value := fmt.Sprint(p)
+	ctxlogrus.AddFields(req.Context(), logrus.Fields{
+		"http.panic":       value,
+		"http.panic.stack": string(stack),
+	})
+	if span := opentracing.SpanFromContext(req.Context()); span != nil {
+		ext.Error.Set(span, true)
+		span.LogFields(
+			log.String("event", "panic"),
+			log.String("message", value),
+			log.String("stack", string(stack)),
+		)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_recovery
+
+import (
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+)
+
+var (
+	defaultOptions = &options{
+		recoveryHandler: DefaultRecoveryHandler,
+	}
+)
+
+type options struct {
+	recoveryHandler RecoveryHandlerFunc
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// RecoveryHandlerFunc handles the response of a request whose handler panicked with the value `p`.
+//
+// Use `resp.StatusCode()` to check whether the headers of the response were already sent.
+type RecoveryHandlerFunc func(resp httpwares.WrappedResponseWriter, req *http.Request, p interface{})
+
+// WithRecoveryHandler customizes how responses of requests whose handler panicked are handled.
+func WithRecoveryHandler(f RecoveryHandlerFunc) Option {
+	return func(o *options) {
+		o.recoveryHandler = f
+	}
+}
+
+// DefaultRecoveryHandler responds with a 500 (Internal Server Error) status code, unless the headers of the response
+// were already sent.
+func DefaultRecoveryHandler(resp httpwares.WrappedResponseWriter, req *http.Request, p interface{}) {
+	if resp.StatusCode() != 0 {
+		return
+	}
+	http.Error(resp, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
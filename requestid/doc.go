@@ -0,0 +1,14 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_requestid` identifies requests with IDs that are propagated from inbound to outbound requests.
+
+The Middleware reads the ID of the request from the `X-Request-Id` header, or generates a new one if there isn't one.
+The ID is put in the request's context (see `Extract`), set as the `http.request.id` tag of `http_ctxtags`, and
+returned in the `X-Request-Id` header of the response.
+
+The Tripperware sets the `X-Request-Id` header of outbound requests made with the context of an inbound request, so
+that a request can be followed through multiple services in their logs, without the need for a tracer.
+*/
+package http_requestid
@@ -0,0 +1,143 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_requestid_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/requestid"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	outboundIdHeader = "x-test-outbound-id"
+	taggedIdHeader   = "x-test-tagged-id"
+)
+
+// propagatingHandler makes an outbound request with the context of the inbound one, and reports back its request ID.
+type propagatingHandler struct {
+	headerName string
+}
+
+func (h *propagatingHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	var outbound *http.Request
+	transport := http_requestid.Tripperware(http_requestid.WithHeaderName(h.headerName))(
+		httpwares.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			outbound = r
+			return &http.Response{StatusCode: http.StatusOK, Request: r}, nil
+		}))
+	outReq, _ := http.NewRequest("GET", "http://upstream.local/", nil)
+	if _, err := transport.RoundTrip(outReq.WithContext(req.Context())); err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	resp.Header().Set(outboundIdHeader, outbound.Header.Get(h.headerName))
+	if id, ok := http_ctxtags.ExtractInbound(req).Values()[http_requestid.TagForRequestID].(string); ok {
+		resp.Header().Set(taggedIdHeader, id)
+	}
+	if outReq.Header.Get(h.headerName) != "" {
+		resp.WriteHeader(http.StatusInternalServerError) // the tripperware must not modify the original request
+		return
+	}
+	resp.WriteHeader(http.StatusOK)
+}
+
+func TestRequestIdSuite(t *testing.T) {
+	s := &RequestIdSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: &propagatingHandler{headerName: "X-Request-Id"},
+			ServerMiddleware: []httpwares.Middleware{
+				http_ctxtags.Middleware("my_group"),
+				http_requestid.Middleware(),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type RequestIdSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *RequestIdSuite) call(inboundId string) *http.Response {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	if inboundId != "" {
+		req.Header.Set("X-Request-Id", inboundId)
+	}
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	require.Equal(s.T(), http.StatusOK, resp.StatusCode, "handler should succeed")
+	return resp
+}
+
+func (s *RequestIdSuite) TestInboundIdIsPropagated() {
+	resp := s.call("my-request-id")
+	assert.Equal(s.T(), "my-request-id", resp.Header.Get("X-Request-Id"), "the id should be returned in the response")
+	assert.Equal(s.T(), "my-request-id", resp.Header.Get(taggedIdHeader), "the id should be set as a tag")
+	assert.Equal(s.T(), "my-request-id", resp.Header.Get(outboundIdHeader), "the id should be set on outbound requests")
+}
+
+func (s *RequestIdSuite) TestIdIsGeneratedIfMissing() {
+	first := s.call("")
+	second := s.call("")
+	id := first.Header.Get("X-Request-Id")
+	assert.Len(s.T(), id, 36, "a UUID should be generated")
+	assert.NotEqual(s.T(), id, second.Header.Get("X-Request-Id"), "each request should get a new id")
+	assert.Equal(s.T(), id, first.Header.Get(taggedIdHeader), "the generated id should be set as a tag")
+	assert.Equal(s.T(), id, first.Header.Get(outboundIdHeader), "the generated id should be set on outbound requests")
+}
+
+func (s *RequestIdSuite) TestInvalidInboundIdIsReplaced() {
+	resp := s.call("not a valid id")
+	id := resp.Header.Get("X-Request-Id")
+	assert.NotEqual(s.T(), "not a valid id", id, "ids with spaces should be replaced")
+	assert.Len(s.T(), id, 36, "a UUID should be generated instead")
+}
+
+func TestCustomRequestIdSuite(t *testing.T) {
+	s := &CustomRequestIdSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: &propagatingHandler{headerName: "X-Correlation-Id"},
+			ServerMiddleware: []httpwares.Middleware{
+				http_requestid.Middleware(
+					http_requestid.WithHeaderName("X-Correlation-Id"),
+					http_requestid.WithGenerator(func() string { return "generated" }),
+				),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type CustomRequestIdSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *CustomRequestIdSuite) TestCustomHeaderAndGeneratorAreUsed() {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	req.Header.Set("X-Request-Id", "ignored")
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	assert.Equal(s.T(), "generated", resp.Header.Get("X-Correlation-Id"), "the custom generator and header should be used")
+	assert.Equal(s.T(), "generated", resp.Header.Get(outboundIdHeader), "the id should be propagated without tags")
+}
+
+func TestTripperwareKeepsExistingHeader(t *testing.T) {
+	var seen string
+	transport := http_requestid.Tripperware()(httpwares.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		seen = r.Header.Get("X-Request-Id")
+		return &http.Response{StatusCode: http.StatusOK, Request: r}, nil
+	}))
+	req, _ := http.NewRequest("GET", "http://upstream.local/", nil)
+	req.Header.Set("X-Request-Id", "explicit")
+	_, err := transport.RoundTrip(req.WithContext(http_requestid.ToContext(req.Context(), "from-context")))
+	require.NoError(t, err)
+	assert.Equal(t, "explicit", seen, "an explicitly set id should not be overwritten")
+}
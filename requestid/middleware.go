@@ -0,0 +1,31 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_requestid
+
+import (
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// Middleware returns a http.Handler middleware that identifies requests.
+//
+// The ID is read from the request header, and generated if the header is missing or holds something that doesn't
+// look like an ID (e.g. is too long). For the ID to be set as a tag, `http_ctxtags.Middleware` needs to be placed
+// before this one in the chain.
+func Middleware(opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			id := req.Header.Get(o.headerName)
+			if !isValidID(id) {
+				id = o.generator()
+			}
+			http_ctxtags.ExtractInbound(req).Set(TagForRequestID, id)
+			resp.Header().Set(o.headerName, id)
+			next.ServeHTTP(resp, req.WithContext(ToContext(req.Context(), id)))
+		})
+	}
+}
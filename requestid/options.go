@@ -0,0 +1,44 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_requestid
+
+var (
+	defaultOptions = &options{
+		headerName: "X-Request-Id",
+		generator:  NewUUID,
+	}
+)
+
+type options struct {
+	headerName string
+	generator  GeneratorFunc
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// GeneratorFunc generates IDs for requests that don't have one.
+type GeneratorFunc func() string
+
+// WithHeaderName sets the name of the header that holds the ID. The default is "X-Request-Id".
+func WithHeaderName(name string) Option {
+	return func(o *options) {
+		o.headerName = name
+	}
+}
+
+// WithGenerator customizes how new IDs are generated. By default random UUIDs are used.
+func WithGenerator(f GeneratorFunc) Option {
+	return func(o *options) {
+		o.generator = f
+	}
+}
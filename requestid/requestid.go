@@ -0,0 +1,65 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// TagForRequestID is the ctxtag holding the ID of the request.
+const TagForRequestID = "http.request.id"
+
+// maxIDLength is the length above which inbound IDs are replaced with generated ones.
+const maxIDLength = 128
+
+type ctxMarker struct{}
+
+var (
+	ctxRequestID = &ctxMarker{}
+)
+
+// Extract returns the ID of the request from its context, or an empty string if there is none.
+//
+// The ID is taken from the context set by the Middleware, or from the `TagForRequestID` tag if it was set in a
+// different way.
+func Extract(ctx context.Context) string {
+	if id, ok := ctx.Value(ctxRequestID).(string); ok {
+		return id
+	}
+	id, _ := http_ctxtags.ExtractInboundFromCtx(ctx).Values()[TagForRequestID].(string)
+	return id
+}
+
+// ToContext returns a context holding the given request ID, for use by the Tripperware.
+func ToContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxRequestID, id)
+}
+
+// NewUUID generates a random (version 4) UUID.
+func NewUUID() string {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		panic(fmt.Sprintf("http_requestid: failed reading random bytes: %v", err))
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+// isValidID checks that an inbound ID is safe to log and propagate.
+func isValidID(id string) bool {
+	if id == "" || len(id) > maxIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
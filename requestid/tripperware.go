@@ -0,0 +1,32 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_requestid
+
+import (
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/internal/header"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// Tripperware returns a new client-side ware that propagates the request ID onto outbound requests.
+//
+// The ID is taken from the context of the request (see `Extract`), so outbound requests need to be made with the
+// context of the inbound request. Requests that already have the header set are left alone.
+func Tripperware(opts ...Option) httpwares.Tripperware {
+	o := evaluateOptions(opts)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			id := Extract(req.Context())
+			if id == "" || req.Header.Get(o.headerName) != "" {
+				return next.RoundTrip(req)
+			}
+			newReq := http_header.CloneRequestWithHeader(req)
+			newReq.Header.Set(o.headerName, id)
+			http_ctxtags.ExtractOutbound(newReq).Set(TagForRequestID, id)
+			return next.RoundTrip(newReq)
+		})
+	}
+}
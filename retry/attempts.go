@@ -0,0 +1,58 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_retry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// TagForAttempts is the outbound tag holding the number of attempts the Tripperware made for a request.
+const TagForAttempts = "http.retry.attempts"
+
+var (
+	ctxAttempts = &ctxMarker{}
+)
+
+// Attempts describes the attempts the Tripperware made for a single request.
+type Attempts struct {
+	// Count is the number of the attempt, starting at 1.
+	Count uint
+	// Errors holds the reasons why each of the previous attempts was discarded, in order. Discarded responses are
+	// represented by `*DiscardedResponseError`.
+	Errors []error
+}
+
+// DiscardedResponseError is the reason for discarding an attempt that returned a response.
+type DiscardedResponseError struct {
+	StatusCode int
+}
+
+func (e *DiscardedResponseError) Error() string {
+	return fmt.Sprintf("response discarded with status code %d", e.StatusCode)
+}
+
+// ExtractAttempts returns the Attempts of the request sent by the Tripperware, or nil if there are none.
+//
+// It is meant to be used on `http.Response.Request` of the returned response, or by Tripperware placed after this
+// one in the chain. If the Tripperware returns an error instead of a response, the Attempts aren't available to the
+// caller, as the returned error is the one of the last attempt (possibly wrapped in a `*BudgetExhaustedError`).
+func ExtractAttempts(req *http.Request) *Attempts {
+	return ExtractAttemptsFromContext(req.Context())
+}
+
+// ExtractAttemptsFromContext returns the Attempts from the context of a request, or nil if there are none.
+func ExtractAttemptsFromContext(ctx context.Context) *Attempts {
+	a, _ := ctx.Value(ctxAttempts).(*Attempts)
+	return a
+}
+
+// attemptError returns the reason for discarding an attempt.
+func attemptError(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	return &DiscardedResponseError{StatusCode: resp.StatusCode}
+}
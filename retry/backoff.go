@@ -1,6 +1,21 @@
 package http_retry
 
-import "time"
+import (
+	"math/rand"
+	"time"
+)
+
+// Jitter denotes the strategy used for randomising exponential backoff durations.
+//
+// See https://www.awsarchitectureblog.com/2015/03/backoff.html for a comparison of the strategies.
+type Jitter int
+
+const (
+	// JitterFull picks a random duration between 0 and the exponential backoff.
+	JitterFull Jitter = iota
+	// JitterEqual keeps half of the exponential backoff and picks the other half at random.
+	JitterEqual
+)
 
 // BackoffLinear is very simple: it waits for a fixed period of time between calls.
 func BackoffLinear(waitBetween time.Duration) BackoffFunc {
@@ -8,3 +23,74 @@ func BackoffLinear(waitBetween time.Duration) BackoffFunc {
 		return waitBetween
 	}
 }
+
+// BackoffExponential produces increasing intervals for each attempt.
+//
+// The wait before the first retry is `base`, and it doubles with each subsequent attempt. It never exceeds `max`.
+func BackoffExponential(base time.Duration, max time.Duration) BackoffFunc {
+	return func(attempt uint) time.Duration {
+		return exponentBase2(attempt, base, max)
+	}
+}
+
+// BackoffExponentialWithJitter creates an exponential backoff like BackoffExponential does, but randomises the wait
+// according to the `jitter` strategy.
+//
+// Jitter prevents clients that failed at the same time from retrying at the same time, and is recommended whenever
+// many clients call the same upstream.
+func BackoffExponentialWithJitter(base time.Duration, max time.Duration, jitter Jitter) BackoffFunc {
+	return func(attempt uint) time.Duration {
+		exp := exponentBase2(attempt, base, max)
+		if jitter == JitterEqual {
+			half := exp / 2
+			return half + randomDuration(exp-half)
+		}
+		return randomDuration(exp)
+	}
+}
+
+// BackoffDecorrelatedJitter waits for a random period between `base` and three times the previous wait, never
+// exceeding `max`.
+//
+// BackoffFunc is shared between requests, so the chain of previous waits is recomputed for each attempt. This keeps
+// the distribution of waits the same as that of the stateful algorithm.
+func BackoffDecorrelatedJitter(base time.Duration, max time.Duration) BackoffFunc {
+	return func(attempt uint) time.Duration {
+		wait := base
+		for i := uint(1); i < attempt; i++ {
+			wait = base + randomDuration(3*wait-base)
+			if wait > max {
+				wait = max
+			}
+		}
+		if wait > max {
+			return max
+		}
+		return wait
+	}
+}
+
+// exponentBase2 computes base * 2^(attempt-1), capped at max and guarded against overflows.
+func exponentBase2(attempt uint, base time.Duration, max time.Duration) time.Duration {
+	if attempt == 0 {
+		return 0
+	}
+	wait := base
+	for i := uint(1); i < attempt; i++ {
+		if wait >= max/2 {
+			return max
+		}
+		wait *= 2
+	}
+	if wait > max {
+		return max
+	}
+	return wait
+}
+
+func randomDuration(upTo time.Duration) time.Duration {
+	if upTo <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(upTo)))
+}
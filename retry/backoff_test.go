@@ -0,0 +1,52 @@
+package http_retry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffExponential(t *testing.T) {
+	backoff := http_retry.BackoffExponential(10*time.Millisecond, 50*time.Millisecond)
+	for attempt, expected := range []time.Duration{0, 10, 20, 40, 50, 50} {
+		assert.Equal(t, expected*time.Millisecond, backoff(uint(attempt)), "attempt %d has the wrong backoff", attempt)
+	}
+	assert.Equal(t, 50*time.Millisecond, backoff(200), "large attempt numbers must not overflow")
+}
+
+func TestBackoffExponentialWithJitter(t *testing.T) {
+	for _, tcase := range []struct {
+		name   string
+		jitter http_retry.Jitter
+		min    []time.Duration
+	}{
+		{name: "full", jitter: http_retry.JitterFull, min: []time.Duration{0, 0, 0, 0}},
+		{name: "equal", jitter: http_retry.JitterEqual, min: []time.Duration{0, 5, 10, 20}},
+	} {
+		t.Run(tcase.name, func(t *testing.T) {
+			backoff := http_retry.BackoffExponentialWithJitter(10*time.Millisecond, 40*time.Millisecond, tcase.jitter)
+			max := []time.Duration{0, 10, 20, 40}
+			for i := 0; i < 100; i++ {
+				for attempt := range max {
+					wait := backoff(uint(attempt))
+					assert.True(t, wait >= tcase.min[attempt]*time.Millisecond, "attempt %d waited too little: %v", attempt, wait)
+					assert.True(t, wait <= max[attempt]*time.Millisecond, "attempt %d waited too long: %v", attempt, wait)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffDecorrelatedJitter(t *testing.T) {
+	backoff := http_retry.BackoffDecorrelatedJitter(10*time.Millisecond, 100*time.Millisecond)
+	assert.Equal(t, 10*time.Millisecond, backoff(1), "first retry must wait for base")
+	for i := 0; i < 100; i++ {
+		for attempt := uint(2); attempt < 10; attempt++ {
+			wait := backoff(attempt)
+			assert.True(t, wait >= 10*time.Millisecond, "attempt %d waited less than base: %v", attempt, wait)
+			assert.True(t, wait <= 100*time.Millisecond, "attempt %d waited more than max: %v", attempt, wait)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_retry
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// TagForBudgetExhausted is the outbound tag set (to true) on requests that were not retried because the retry
+	// `Budget` was exhausted.
+	TagForBudgetExhausted = "http.retry.budget_exhausted"
+
+	budgetBuckets = 10
+)
+
+// BudgetExhaustedError is returned by the Tripperware if the last attempt of a request failed with an error, and
+// the request could not be retried because the retry `Budget` was exhausted.
+type BudgetExhaustedError struct {
+	// Err is the error of the last attempt.
+	Err error
+}
+
+func (e *BudgetExhaustedError) Error() string {
+	return fmt.Sprintf("retry budget exhausted, last error: %v", e.Err)
+}
+
+// Budget limits the amount of retries in relation to the amount of requests, preventing retry storms.
+//
+// Every request that goes through a Tripperware using the Budget and could be retried is counted, and every retry
+// needs to be allowed by the Budget. Requests that are never retried, e.g. non-idempotent ones, don't count. Both are kept over a sliding window of `ttl`. The Budget is safe for concurrent use and can (and
+// should) be shared between multiple Tripperware instances calling the same upstream.
+type Budget struct {
+	mu             sync.Mutex
+	bucketWidth    time.Duration
+	minRetries     float64
+	percentRetries float64
+	buckets        [budgetBuckets]budgetBucket
+	clock          func() time.Time
+}
+
+type budgetBucket struct {
+	epoch    int64
+	requests uint64
+	retries  uint64
+}
+
+// NewBudget creates a Budget that allows `percentCanRetry` percent of requests (e.g. 10.0) to be retried over a
+// sliding window of `ttl`.
+//
+// In order to allow retries when the traffic is low, `minRetriesPerSec` retries per second are always allowed on top
+// of the percentage.
+func NewBudget(ttl time.Duration, minRetriesPerSec uint, percentCanRetry float64) *Budget {
+	if ttl < budgetBuckets {
+		ttl = budgetBuckets
+	}
+	return &Budget{
+		bucketWidth:    ttl / budgetBuckets,
+		minRetries:     float64(minRetriesPerSec) * ttl.Seconds(),
+		percentRetries: percentCanRetry / 100,
+		clock:          time.Now,
+	}
+}
+
+// deposit records a request.
+func (b *Budget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.currentBucket().requests++
+}
+
+// withdraw records a retry, returning false if the retry is not allowed.
+func (b *Budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	current := b.currentBucket()
+	var requests, retries uint64
+	for i := range b.buckets {
+		if b.buckets[i].epoch > current.epoch-budgetBuckets {
+			requests += b.buckets[i].requests
+			retries += b.buckets[i].retries
+		}
+	}
+	if float64(retries) >= b.minRetries+b.percentRetries*float64(requests) {
+		return false
+	}
+	current.retries++
+	return true
+}
+
+func (b *Budget) currentBucket() *budgetBucket {
+	epoch := b.clock().UnixNano() / int64(b.bucketWidth)
+	bucket := &b.buckets[epoch%budgetBuckets]
+	if bucket.epoch != epoch {
+		*bucket = budgetBucket{epoch: epoch}
+	}
+	return bucket
+}
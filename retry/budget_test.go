@@ -0,0 +1,90 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_retry
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetAllowsPercentageOfRequests(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBudget(10*time.Second, 0, 10.0)
+	b.clock = func() time.Time { return now }
+	for i := 0; i < 20; i++ {
+		b.deposit()
+	}
+	assert.True(t, b.withdraw(), "first retry out of 20 requests should be allowed")
+	assert.True(t, b.withdraw(), "second retry out of 20 requests should be allowed")
+	assert.False(t, b.withdraw(), "third retry out of 20 requests should be rejected")
+}
+
+func TestBudgetAllowsMinimumRetries(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBudget(2*time.Second, 1, 0)
+	b.clock = func() time.Time { return now }
+	assert.True(t, b.withdraw(), "first retry should be allowed by the minimum")
+	assert.True(t, b.withdraw(), "second retry should be allowed by the minimum")
+	assert.False(t, b.withdraw(), "the minimum should be 2 retries over 2 seconds")
+}
+
+func TestBudgetWindowSlides(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBudget(10*time.Second, 0, 50.0)
+	b.clock = func() time.Time { return now }
+	b.deposit()
+	b.deposit()
+	require.True(t, b.withdraw(), "retry should be allowed")
+	require.False(t, b.withdraw(), "retry should be rejected")
+	now = now.Add(5 * time.Second)
+	require.False(t, b.withdraw(), "retries from 5s ago should still count")
+	now = now.Add(6 * time.Second)
+	assert.False(t, b.withdraw(), "no requests should be in the window")
+	b.deposit()
+	assert.True(t, b.withdraw(), "the first requests and retries should be outside of the window")
+}
+
+func TestTripperwareReturnsBudgetExhaustedError(t *testing.T) {
+	transportErr := errors.New("connection refused")
+	calls := 0
+	failing := httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return nil, transportErr
+	})
+	rt := Tripperware(WithMax(5), WithBackoff(BackoffLinear(0)), WithBudget(NewBudget(time.Second, 0, 0)))(failing)
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	_, err := rt.RoundTrip(req)
+	require.Error(t, err, "call should fail")
+	budgetErr, ok := err.(*BudgetExhaustedError)
+	require.True(t, ok, "error should be a BudgetExhaustedError, got %v", err)
+	assert.Equal(t, transportErr, budgetErr.Err, "the last error should be wrapped")
+	assert.Equal(t, 1, calls, "no retries should happen with an empty budget")
+}
+
+func TestTripperwareOnlyDepositsRetriableRequests(t *testing.T) {
+	budget := NewBudget(time.Second, 0, 100.0)
+	calls := 0
+	failing := httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Request: req}, nil
+	})
+	rt := Tripperware(WithMax(5), WithBackoff(BackoffLinear(0)), WithBudget(budget))(failing)
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest("POST", "https://something.local/someurl", nil)
+		_, err := rt.RoundTrip(req)
+		require.NoError(t, err, "call shouldn't fail")
+	}
+	require.Equal(t, 3, calls, "non-idempotent requests must not be retried")
+	calls = 0
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	_, err := rt.RoundTrip(req)
+	require.NoError(t, err, "call shouldn't fail")
+	assert.Equal(t, 2, calls, "only the GET request should count towards the budget, allowing a single retry")
+}
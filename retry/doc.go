@@ -6,6 +6,6 @@
 
 This logic works for requests considered safe and idempotent (configurable) and ones that have no body, or have `Request.GetBody` either automatically implemented (`byte.Buffer`, `string.Buffer`) or specified manually. By default all GET, HEAD and OPTIONS requests are considered idempotent and safe.
 
-The implementation allow retries after client-side errors or returned error codes (by default 5**) according to configurable backoff policies (linear backoff by default). Additionally, requests can be hedged. Hedging works by sending additional requests without waiting for a previous one to return.
+The implementation allow retries after client-side errors or returned error codes (by default 5**) according to configurable backoff policies (linear backoff by default, with exponential and jittered backoffs available). Rate-limited (429) responses can be retried too, honouring the server's `Retry-After` header. A `Budget` shared between Tripperwares can cap retries to a percentage of requests, preventing retry storms during outages. Additionally, requests can be hedged. Hedging works by sending additional requests without waiting for a previous one to return.
 */
 package http_retry
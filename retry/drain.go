@@ -0,0 +1,36 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_retry
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// discardResponse drains (up to maxBytes) and closes the body of a response that won't be returned to the caller.
+//
+// Draining the body allows the `http.Transport` to reuse the connection of HTTP/1.x responses.
+func discardResponse(resp *http.Response, maxBytes int64) {
+	if resp.Body == nil {
+		return
+	}
+	if maxBytes > 0 {
+		io.CopyN(ioutil.Discard, resp.Body, maxBytes)
+	}
+	resp.Body.Close()
+}
+
+// cancelOnCloseBody releases the context of the attempt that returned the body once the body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,44 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_retry
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type closeTrackingBody struct {
+	*strings.Reader
+	closed int32
+}
+
+func (b *closeTrackingBody) Close() error {
+	atomic.StoreInt32(&b.closed, 1)
+	return nil
+}
+
+func TestDiscardedResponseIsClosedWhenContextIsDoneDuringBackoff(t *testing.T) {
+	body := &closeTrackingBody{Reader: strings.NewReader("SomethingWentWrong")}
+	failing := httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: body, Request: req}, nil
+	})
+	rt := Tripperware(WithMax(3), WithBackoff(BackoffLinear(time.Minute)))(failing)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	_, err := rt.RoundTrip(req.WithContext(ctx))
+	require.Equal(t, context.Canceled, err, "the context error should be returned")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&body.closed), "the body of the discarded response must be closed")
+	rest, _ := ioutil.ReadAll(body)
+	assert.Empty(t, rest, "the body of the discarded response must be drained")
+}
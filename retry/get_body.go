@@ -0,0 +1,16 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_retry
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares/internal/requestbody"
+)
+
+// Wrap the Body of the Request so it can be read repeatedly in case of retrying
+func getBody(r *http.Request) func() (io.ReadCloser, error) {
+	return http_requestbody.GetBody(r)
+}
@@ -0,0 +1,247 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_retry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/internal/header"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+const (
+	hedgeLatencyWindow     = 100
+	hedgeLatencyMinSamples = 10
+)
+
+// HedgingTripperware is client side HTTP ware that hedges requests against slow responses.
+//
+// If a request doesn't return within the hedge delay, a second copy of it is sent. Whichever of the two returns a
+// response first that isn't discarded by the `ResponseDiscarderFunc` wins, and the other one is cancelled and drained.
+// If both fail, the result of the one that finished last is returned.
+//
+// Each attempt gets its own copy of the outbound `http_ctxtags`, as the attempts run concurrently. The tags of the
+// attempt whose result is returned are copied back to the ones of the request.
+//
+// Hedging only applies to requests that the `RequestRetryDeciderFunc` considers safe and idempotent, or that have
+// `http_retry.Enable` set on them. By default the hedge is sent after 100ms, see `WithHedgeDelay` and
+// `WithHedgeLatencyPercentile`. The `WithMax` and `WithBackoff` options have no effect on hedging.
+func HedgingTripperware(opts ...Option) httpwares.Tripperware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		o := evaluateOptions(opts)
+		var latencies *latencyTracker
+		if o.hedgePercentile > 0 {
+			latencies = &latencyTracker{}
+		}
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !o.decider(req) && !isEnabled(req.Context()) {
+				return next.RoundTrip(req)
+			}
+			getBodyFn := getConcurrentBody(req)
+			if getBodyFn == nil {
+				return next.RoundTrip(req)
+			}
+			delay := o.hedgeDelay
+			if latencies != nil {
+				if percentile, ok := latencies.percentile(o.hedgePercentile); ok {
+					delay = percentile
+				}
+			}
+			tags := http_ctxtags.ExtractOutbound(req)
+			results := make(chan *hedgeResult, 2)
+			var cancels []context.CancelFunc
+			cancel, err := startHedgeAttempt(0, next, req, getBodyFn, results)
+			if err != nil {
+				return nil, err
+			}
+			cancels = append(cancels, cancel)
+			inFlight := 1
+			hedgeTimer := time.NewTimer(delay)
+			defer hedgeTimer.Stop()
+			var last *hedgeResult
+			for inFlight > 0 {
+				select {
+				case <-hedgeTimer.C:
+					if req.Context().Err() != nil {
+						continue // no point hedging if the caller gave up
+					}
+					if cancel, err := startHedgeAttempt(len(cancels), next, req, getBodyFn, results); err == nil {
+						cancels = append(cancels, cancel)
+						inFlight++
+					}
+				case result := <-results:
+					inFlight--
+					if result.err == nil && !o.shouldDiscard(result.resp) {
+						if latencies != nil {
+							latencies.observe(result.latency)
+						}
+						for attempt, cancel := range cancels {
+							if attempt != result.attempt {
+								cancel() // the losers are drained once they return
+							}
+						}
+						discardHedgeResults(results, inFlight, o.maxDrainBytes)
+						if last != nil {
+							last.discard(o.maxDrainBytes)
+						}
+						result.copyTagsTo(tags)
+						return result.response(), nil
+					}
+					if last != nil {
+						last.discard(o.maxDrainBytes)
+					}
+					last = result
+					if inFlight == 0 {
+						hedgeTimer.Stop() // a failed request is not hedged, that's what retries are for
+					}
+				}
+			}
+			last.copyTagsTo(tags)
+			if last.err != nil {
+				last.cancel()
+				return nil, last.err
+			}
+			return last.response(), nil
+		})
+	}
+}
+
+type hedgeResult struct {
+	attempt int
+	resp    *http.Response
+	err     error
+	cancel  context.CancelFunc
+	latency time.Duration
+	tags    *http_ctxtags.Tags
+}
+
+func (r *hedgeResult) copyTagsTo(tags *http_ctxtags.Tags) {
+	for k, v := range r.tags.Values() {
+		tags.Set(k, v)
+	}
+}
+
+// response returns the response, making sure that closing its body releases the context of the attempt.
+func (r *hedgeResult) response() *http.Response {
+	if r.resp.Body == nil {
+		r.cancel()
+		return r.resp
+	}
+	r.resp.Body = &cancelOnCloseBody{ReadCloser: r.resp.Body, cancel: r.cancel}
+	return r.resp
+}
+
+func (r *hedgeResult) discard(maxDrainBytes int64) {
+	if r.resp != nil {
+		discardResponse(r.resp, maxDrainBytes)
+	}
+	r.cancel()
+}
+
+func startHedgeAttempt(attempt int, next http.RoundTripper, req *http.Request, getBodyFn func() (io.ReadCloser, error), results chan<- *hedgeResult) (context.CancelFunc, error) {
+	ctx, tags := http_ctxtags.CopyOutboundToCtx(req.Context())
+	ctx, cancel := context.WithCancel(ctx)
+	thisReq := req.WithContext(ctx) // make a copy.
+	thisReq.Header = http_header.Clone(req.Header)
+	var err error
+	thisReq.Body, err = getBodyFn()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed reading body for hedging: %v", err)
+	}
+	go func() {
+		start := time.Now()
+		resp, err := next.RoundTrip(thisReq)
+		results <- &hedgeResult{attempt: attempt, resp: resp, err: err, cancel: cancel, latency: time.Since(start), tags: tags}
+	}()
+	return cancel, nil
+}
+
+// discardHedgeResults drains the attempts that lost the race, in the background.
+func discardHedgeResults(results <-chan *hedgeResult, inFlight int, maxDrainBytes int64) {
+	if inFlight == 0 {
+		return
+	}
+	go func() {
+		for i := 0; i < inFlight; i++ {
+			(<-results).discard(maxDrainBytes)
+		}
+	}()
+}
+
+// getConcurrentBody is like getBody, but the returned bodies can be read at the same time.
+//
+// Bodies replayed by seeking share their position, so they are buffered in memory instead.
+func getConcurrentBody(r *http.Request) func() (io.ReadCloser, error) {
+	getBodyFn := getBody(r)
+	if _, ok := r.Body.(io.ReadSeeker); !ok || getBodyFn == nil {
+		return getBodyFn
+	}
+	body, err := getBodyFn()
+	if err != nil {
+		return func() (io.ReadCloser, error) {
+			return nil, err
+		}
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return func() (io.ReadCloser, error) {
+			return nil, err
+		}
+	}
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// latencyTracker keeps a window of the most recent latencies of successful requests.
+type latencyTracker struct {
+	mu      sync.Mutex
+	samples [hedgeLatencyWindow]time.Duration
+	count   int
+}
+
+func (l *latencyTracker) observe(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.samples[l.count%hedgeLatencyWindow] = latency
+	l.count++
+}
+
+// percentile returns the given percentile (0-100) of the observed latencies, or false if too few were observed.
+func (l *latencyTracker) percentile(percentile float64) (time.Duration, bool) {
+	l.mu.Lock()
+	n := l.count
+	if n > hedgeLatencyWindow {
+		n = hedgeLatencyWindow
+	}
+	if n < hedgeLatencyMinSamples {
+		l.mu.Unlock()
+		return 0, false
+	}
+	sorted := make(durations, n)
+	copy(sorted, l.samples[:n])
+	l.mu.Unlock()
+	sort.Sort(sorted)
+	idx := int(percentile / 100 * float64(n))
+	if idx >= n {
+		idx = n - 1
+	}
+	return sorted[idx], true
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
@@ -0,0 +1,28 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyTrackerPercentile(t *testing.T) {
+	l := &latencyTracker{}
+	for i := 1; i < hedgeLatencyMinSamples; i++ {
+		l.observe(time.Duration(i) * time.Millisecond)
+	}
+	_, ok := l.percentile(90)
+	assert.False(t, ok, "percentile shouldn't be available with too few samples")
+	for i := 1; i <= 2*hedgeLatencyWindow; i++ {
+		l.observe(time.Duration(i) * time.Millisecond)
+	}
+	p, ok := l.percentile(90)
+	assert.True(t, ok, "percentile should be available")
+	assert.Equal(t, 191*time.Millisecond, p, "only the most recent samples should be used")
+	p, _ = l.percentile(100)
+	assert.Equal(t, 200*time.Millisecond, p, "100th percentile should be the max")
+}
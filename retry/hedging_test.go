@@ -0,0 +1,174 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_retry_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/retry"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/net/context"
+)
+
+var (
+	hedgeDelay = 50 * time.Millisecond
+	slowDelay  = 2 * time.Second
+)
+
+// slowFirstHandler makes the first request it sees hang, while the others return immediately.
+type slowFirstHandler struct {
+	mu        sync.Mutex
+	reqCount  uint
+	cancelled chan struct{}
+}
+
+func (h *slowFirstHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	h.mu.Lock()
+	h.reqCount += 1
+	first := h.reqCount == 1
+	cancelled := h.cancelled
+	h.mu.Unlock()
+	if first {
+		select {
+		case <-req.Context().Done():
+			close(cancelled)
+			return
+		case <-time.After(slowDelay):
+		}
+	}
+	httpwares_testing.PingBackHandler(httpwares_testing.DefaultPingBackStatusCode).ServeHTTP(resp, req)
+}
+
+func (h *slowFirstHandler) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.reqCount = 0
+	h.cancelled = make(chan struct{})
+}
+
+func (h *slowFirstHandler) requestCount() uint {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.reqCount
+}
+
+func TestHedgingTripperwareSuite(t *testing.T) {
+	h := &slowFirstHandler{}
+	s := &HedgingTripperwareSuite{
+		h: h,
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: h,
+			ClientTripperware: []httpwares.Tripperware{
+				http_retry.HedgingTripperware(http_retry.WithHedgeDelay(hedgeDelay)),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type HedgingTripperwareSuite struct {
+	*httpwares_testing.WaresTestSuite
+	h *slowFirstHandler
+}
+
+func (s *HedgingTripperwareSuite) SetupTest() {
+	s.h.reset()
+}
+
+func (s *HedgingTripperwareSuite) TestHedgedRequestWins() {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	start := time.Now()
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "response should succeed")
+	assert.True(s.T(), time.Since(start) < slowDelay/2, "the hedged request should return before the slow one")
+	assert.EqualValues(s.T(), 2, s.h.requestCount(), "backend should see the original and the hedged request")
+	select {
+	case <-s.h.cancelled:
+	case <-time.After(slowDelay / 2):
+		s.T().Errorf("the slow request should have been cancelled")
+	}
+}
+
+func (s *HedgingTripperwareSuite) TestHedgingReplaysTheBody() {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", strings.NewReader(expectedContent))
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "response should succeed")
+	assert.EqualValues(s.T(), 2, s.h.requestCount(), "backend should see the original and the hedged request")
+}
+
+func (s *HedgingTripperwareSuite) TestNonIdempotentRequestsAreNotHedged() {
+	req, _ := http.NewRequest("POST", "https://something.local/someurl", nil)
+	ctx, cancel := context.WithTimeout(s.SimpleCtx(), 4*hedgeDelay)
+	defer cancel()
+	_, err := s.NewClient().Do(req.WithContext(ctx))
+	require.Error(s.T(), err, "the slow request should time out, as it shouldn't be hedged")
+	assert.EqualValues(s.T(), 1, s.h.requestCount(), "backend should see only one request")
+}
+
+func (s *HedgingTripperwareSuite) TestFastRequestsAreNotHedged() {
+	s.h.mu.Lock()
+	s.h.reqCount = 1 // skip the slow request
+	s.h.mu.Unlock()
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	resp.Body.Close()
+	time.Sleep(2 * hedgeDelay)
+	assert.EqualValues(s.T(), 2, s.h.requestCount(), "backend should see only one more request")
+}
+
+func TestHedgedAttemptsDontShareTags(t *testing.T) {
+	var attempts int32
+	// tagWriter stands for tripperware setting tags below the hedging, e.g. a circuit breaker.
+	tagWriter := httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		attempt := atomic.AddInt32(&attempts, 1)
+		tags := http_ctxtags.ExtractOutbound(req)
+		if attempt == 1 {
+			for i := 0; req.Context().Err() == nil; i++ {
+				tags.Set(fmt.Sprintf("slow.%d", i%100), i)
+				time.Sleep(time.Millisecond)
+			}
+			return nil, req.Context().Err()
+		}
+		for i := 0; i < 100; i++ {
+			tags.Set(fmt.Sprintf("hedge.%d", i), i)
+		}
+		tags.Set("winner", attempt)
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader("")), Request: req}, nil
+	})
+	var callerTags *http_ctxtags.Tags
+	captureTags := func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			callerTags = http_ctxtags.ExtractOutbound(req)
+			return next.RoundTrip(req)
+		})
+	}
+	client := httpwares.WrapClient(&http.Client{Transport: tagWriter},
+		http_ctxtags.Tripperware(),
+		captureTags,
+		http_retry.HedgingTripperware(http_retry.WithHedgeDelay(10*time.Millisecond)),
+	)
+
+	resp, err := client.Get("https://something.local/someurl")
+	require.NoError(t, err, "call shouldn't fail")
+	resp.Body.Close()
+	assert.EqualValues(t, 2, callerTags.Values()["winner"], "the tags of the winning attempt should be copied to the caller's")
+	assert.False(t, callerTags.Has("slow.0"), "the tags of the losing attempt shouldn't be copied")
+}
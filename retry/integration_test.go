@@ -7,6 +7,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
 	"strings"
 	"sync"
 	"testing"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/improbable-eng/go-httpwares"
 	"github.com/improbable-eng/go-httpwares/retry"
+	"github.com/improbable-eng/go-httpwares/tags"
 	"github.com/improbable-eng/go-httpwares/testing"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -26,6 +28,7 @@ var (
 	retryTimeout    = 50 * time.Millisecond
 	failureCode     = http.StatusServiceUnavailable
 	expectedContent = "SomeReallyLongString"
+	failureContent  = "SomethingWentWrong"
 )
 
 type failingHandler struct {
@@ -33,12 +36,19 @@ type failingHandler struct {
 	reqCounter uint
 	reqModulo  uint
 	reqSleep   time.Duration
+	failCode   int
+	retryAfter string
 	mu         sync.Mutex
 }
 
 func (f *failingHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
-	if f.maybeFailRequest() {
-		resp.WriteHeader(failureCode)
+	if code, retryAfter, sleep, fail := f.maybeFailRequest(); fail {
+		time.Sleep(sleep)
+		if retryAfter != "" {
+			resp.Header().Set("Retry-After", retryAfter)
+		}
+		resp.WriteHeader(code)
+		resp.Write([]byte(failureContent))
 		return
 	}
 	content, err := ioutil.ReadAll(req.Body)
@@ -53,6 +63,15 @@ func (f *failingHandler) resetFailingConfiguration(modulo uint, sleepTime time.D
 	f.reqCounter = 0
 	f.reqModulo = modulo
 	f.reqSleep = sleepTime
+	f.failCode = failureCode
+	f.retryAfter = ""
+}
+
+func (f *failingHandler) setFailureResponse(code int, retryAfter string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failCode = code
+	f.retryAfter = retryAfter
 }
 
 func (f *failingHandler) requestCount() uint {
@@ -61,15 +80,14 @@ func (f *failingHandler) requestCount() uint {
 	return f.reqCounter
 }
 
-func (f *failingHandler) maybeFailRequest() bool {
+func (f *failingHandler) maybeFailRequest() (code int, retryAfter string, sleep time.Duration, fail bool) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	f.reqCounter += 1
 	if (f.reqModulo > 0) && (f.reqCounter%f.reqModulo == 0) {
-		return false
+		return 0, "", 0, false
 	}
-	time.Sleep(f.reqSleep)
-	return true
+	return f.failCode, f.retryAfter, f.reqSleep, true
 }
 
 func TestRetryTripperwareSuite(t *testing.T) {
@@ -144,14 +162,209 @@ func (s *RetryTripperwareSuite) TestRetryWorksWithAnEnable() {
 }
 
 func (s *RetryTripperwareSuite) TestTimesoutTheContextAnyway() {
-	s.f.resetFailingConfiguration(4, noSleep)
-	ctx, _ := context.WithTimeout(s.SimpleCtx(), 2*retryTimeout) // should be enough for 2 calls
+	s.f.resetFailingConfiguration(4, 2*retryTimeout)
+	ctx, _ := context.WithTimeout(s.SimpleCtx(), 4*retryTimeout) // should be enough to start 2 calls, not to finish them
 	req := s.createRequest("GET", ctx)                           // GET is retriable
 	_, err := s.NewClient().Do(req)
 	require.Error(s.T(), err, "call should fail with a context deadline exceeded")
 	require.EqualValues(s.T(), 2, s.f.requestCount(), "backend should see two calls")
 }
 
+func (s *RetryTripperwareSuite) TestSkipsAttemptsPastTheDeadline() {
+	s.f.resetFailingConfiguration(4, noSleep)
+	clientTripperware := s.ClientTripperware
+	s.ClientTripperware = []httpwares.Tripperware{
+		http_retry.Tripperware(
+			http_retry.WithMax(5),
+			http_retry.WithBackoff(http_retry.BackoffLinear(4*retryTimeout)),
+		),
+	}
+	ctx, _ := context.WithTimeout(s.SimpleCtx(), 2*retryTimeout) // not enough to wait for the backoff
+	req := s.createRequest("GET", ctx)                           // GET is retriable
+	start := time.Now()
+	resp, err := s.NewClient().Do(req)
+	require.NoError(s.T(), err, "call shouldn't fail, the last response should be returned")
+	assert.Equal(s.T(), failureCode, resp.StatusCode, "failure code should be propagated")
+	assert.True(s.T(), time.Since(start) < 2*retryTimeout, "the call shouldn't wait for the deadline to pass")
+	require.EqualValues(s.T(), 1, s.f.requestCount(), "backend should see only the first call")
+	s.ClientTripperware = clientTripperware
+}
+
+func (s *RetryTripperwareSuite) TestExponentialBackoff() {
+	s.f.resetFailingConfiguration(3, noSleep)
+	clientTripperware := s.ClientTripperware
+	s.ClientTripperware = []httpwares.Tripperware{
+		http_retry.Tripperware(
+			http_retry.WithMax(5),
+			http_retry.WithBackoff(http_retry.BackoffExponentialWithJitter(retryTimeout, 4*retryTimeout, http_retry.JitterEqual)),
+		),
+	}
+	req := s.createRequest("GET", s.SimpleCtx())
+	start := time.Now()
+	resp, err := s.NewClient().Do(req)
+	require.NoError(s.T(), err, "call shouldn't fail")
+	require.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "response should succeed")
+	assert.True(s.T(), time.Since(start) >= 3*retryTimeout/2, "equal jitter should wait at least half of 50ms and 100ms")
+	require.EqualValues(s.T(), 3, s.f.requestCount(), "3 requests should be retried to meet the modulo")
+	s.ClientTripperware = clientTripperware
+}
+
+func (s *RetryTripperwareSuite) TestTooManyRequestsNotRetriedByDefault() {
+	s.f.resetFailingConfiguration(3, noSleep)
+	s.f.setFailureResponse(http.StatusTooManyRequests, "")
+	req := s.createRequest("GET", s.SimpleCtx())
+	resp, err := s.NewClient().Do(req)
+	require.NoError(s.T(), err, "call shouldn't fail")
+	assert.Equal(s.T(), http.StatusTooManyRequests, resp.StatusCode, "429 should be propagated")
+	require.EqualValues(s.T(), 1, s.f.requestCount(), "backend should see only one request")
+}
+
+func (s *RetryTripperwareSuite) TestTooManyRequestsHonoursRetryAfter() {
+	s.f.resetFailingConfiguration(3, noSleep)
+	s.f.setFailureResponse(http.StatusTooManyRequests, "1")
+	clientTripperware := s.ClientTripperware
+	s.ClientTripperware = []httpwares.Tripperware{
+		http_retry.Tripperware(
+			http_retry.WithMax(5),
+			http_retry.WithBackoff(http_retry.BackoffLinear(0)),
+			http_retry.WithRetryOnTooManyRequests(),
+			http_retry.WithRetryAfter(2*retryTimeout),
+		),
+	}
+	req := s.createRequest("GET", s.SimpleCtx())
+	start := time.Now()
+	resp, err := s.NewClient().Do(req)
+	elapsed := time.Since(start)
+	require.NoError(s.T(), err, "call shouldn't fail")
+	require.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "response should succeed")
+	require.EqualValues(s.T(), 3, s.f.requestCount(), "3 requests should be retried to meet the modulo")
+	assert.True(s.T(), elapsed >= 4*retryTimeout, "both retries should wait for the capped Retry-After, waited %v", elapsed)
+	assert.True(s.T(), elapsed < time.Second, "Retry-After of 1s should be capped, waited %v", elapsed)
+	s.ClientTripperware = clientTripperware
+}
+
+func (s *RetryTripperwareSuite) TestRetryAfterPastDeadlineReturnsLastResponse() {
+	s.f.resetFailingConfiguration(3, noSleep)
+	s.f.setFailureResponse(failureCode, "60")
+	clientTripperware := s.ClientTripperware
+	s.ClientTripperware = []httpwares.Tripperware{
+		http_retry.Tripperware(
+			http_retry.WithMax(5),
+			http_retry.WithBackoff(http_retry.BackoffLinear(0)),
+			http_retry.WithRetryAfter(time.Minute),
+		),
+	}
+	ctx, _ := context.WithTimeout(s.SimpleCtx(), 4*retryTimeout)
+	req := s.createRequest("GET", ctx)
+	resp, err := s.NewClient().Do(req)
+	require.NoError(s.T(), err, "call shouldn't fail, the last response should be returned")
+	assert.Equal(s.T(), failureCode, resp.StatusCode, "failure code should be propagated")
+	assert.Equal(s.T(), "60", resp.Header.Get("Retry-After"), "Retry-After should be propagated")
+	require.EqualValues(s.T(), 1, s.f.requestCount(), "backend should see only the first call")
+	s.ClientTripperware = clientTripperware
+}
+
+func (s *RetryTripperwareSuite) TestBudgetIsSharedBetweenTripperwares() {
+	s.f.resetFailingConfiguration(10, noSleep)
+	budget := http_retry.NewBudget(time.Minute, 0, 50.0)
+	clientTripperware := s.ClientTripperware
+	s.ClientTripperware = []httpwares.Tripperware{
+		http_ctxtags.Tripperware(),
+		http_retry.Tripperware(http_retry.WithMax(5), http_retry.WithBackoff(http_retry.BackoffLinear(0)), http_retry.WithBudget(budget)),
+	}
+	firstClient := s.NewClient()
+	s.ClientTripperware = []httpwares.Tripperware{
+		http_ctxtags.Tripperware(),
+		http_retry.Tripperware(http_retry.WithMax(5), http_retry.WithBackoff(http_retry.BackoffLinear(0)), http_retry.WithBudget(budget)),
+	}
+	secondClient := s.NewClient()
+	s.ClientTripperware = clientTripperware
+
+	resp, err := firstClient.Do(s.createRequest("GET", s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	assert.Equal(s.T(), failureCode, resp.StatusCode, "failure code should be propagated")
+	assert.EqualValues(s.T(), 2, s.f.requestCount(), "the first request should use up the budget with a single retry")
+	assert.True(s.T(), http_ctxtags.ExtractOutbound(resp.Request).Has(http_retry.TagForBudgetExhausted), "exhaustion should be tagged")
+
+	resp, err = secondClient.Do(s.createRequest("GET", s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	assert.Equal(s.T(), failureCode, resp.StatusCode, "failure code should be propagated")
+	assert.EqualValues(s.T(), 3, s.f.requestCount(), "the second request should get no retries from the shared budget")
+	assert.True(s.T(), http_ctxtags.ExtractOutbound(resp.Request).Has(http_retry.TagForBudgetExhausted), "exhaustion should be tagged")
+}
+
+func (s *RetryTripperwareSuite) TestAttemptsAreExposed() {
+	s.f.resetFailingConfiguration(3, noSleep)
+	clientTripperware := s.ClientTripperware
+	s.ClientTripperware = []httpwares.Tripperware{http_ctxtags.Tripperware(), clientTripperware[0]}
+	req := s.createRequest("GET", s.SimpleCtx())
+	resp, err := s.NewClient().Do(req)
+	s.ClientTripperware = clientTripperware
+	require.NoError(s.T(), err, "call shouldn't fail")
+	require.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "response should succeed")
+	attempts := http_retry.ExtractAttempts(resp.Request)
+	require.NotNil(s.T(), attempts, "attempts should be available on the response's request")
+	assert.EqualValues(s.T(), 3, attempts.Count, "the response should come from the third attempt")
+	require.Len(s.T(), attempts.Errors, 2, "two attempts should have been discarded")
+	for _, err := range attempts.Errors {
+		discardErr, ok := err.(*http_retry.DiscardedResponseError)
+		require.True(s.T(), ok, "discarded responses should be reported as DiscardedResponseError, got %v", err)
+		assert.Equal(s.T(), failureCode, discardErr.StatusCode, "the discarded status code should be reported")
+	}
+	assert.EqualValues(s.T(), 3, http_ctxtags.ExtractOutbound(resp.Request).Values()[http_retry.TagForAttempts], "attempts should be tagged")
+}
+
+func (s *RetryTripperwareSuite) TestPerRetryTimeout() {
+	s.f.resetFailingConfiguration(3, 4*retryTimeout)
+	clientTripperware := s.ClientTripperware
+	s.ClientTripperware = []httpwares.Tripperware{
+		http_retry.Tripperware(
+			http_retry.WithMax(5),
+			http_retry.WithBackoff(http_retry.BackoffLinear(0)),
+			http_retry.WithPerRetryTimeout(retryTimeout),
+		),
+	}
+	req := s.createRequest("GET", s.SimpleCtx())
+	start := time.Now()
+	resp, err := s.NewClient().Do(req)
+	s.ClientTripperware = clientTripperware
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	require.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "response should succeed")
+	assert.True(s.T(), time.Since(start) < 4*retryTimeout, "hung attempts should time out on their own")
+	attempts := http_retry.ExtractAttempts(resp.Request)
+	require.NotNil(s.T(), attempts, "attempts should be available on the response's request")
+	assert.EqualValues(s.T(), 3, attempts.Count, "the response should come from the third attempt")
+	assert.Len(s.T(), attempts.Errors, 2, "two attempts should have timed out")
+	_, err = ioutil.ReadAll(resp.Body)
+	assert.NoError(s.T(), err, "the body of the last attempt should be readable")
+}
+
+func (s *RetryTripperwareSuite) TestConnectionsAreReusedAcrossRetries() {
+	s.f.resetFailingConfiguration(3, noSleep)
+	s.ClientInLegacyHttp1Mode = true // HTTP/2 multiplexes requests over a single connection anyway
+	client := s.NewClient()
+	s.ClientInLegacyHttp1Mode = false
+	var mu sync.Mutex
+	var reused []bool
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			reused = append(reused, info.Reused)
+		},
+	}
+	req := s.createRequest("GET", httptrace.WithClientTrace(s.SimpleCtx(), trace))
+	resp, err := client.Do(req)
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	require.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "response should succeed")
+	require.EqualValues(s.T(), 3, s.f.requestCount(), "3 requests should be retried to meet the modulo")
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(s.T(), []bool{false, true, true}, reused, "retries should reuse the connection of the discarded responses")
+}
+
 func (s *RetryTripperwareSuite) TestCustomRetriableDecider() {
 	s.f.resetFailingConfiguration(10, noSleep)
 	clientTripperware := s.ClientTripperware
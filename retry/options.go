@@ -10,18 +10,27 @@ import (
 
 var (
 	defaultOptions = &options{
-		decider:     DefaultRetriableDecider,
-		discarder:   DefaultResponseDiscarder,
-		maxRetry:    3,
-		backoffFunc: BackoffLinear(100 * time.Millisecond),
+		decider:       DefaultRetriableDecider,
+		discarder:     DefaultResponseDiscarder,
+		maxRetry:      3,
+		backoffFunc:   BackoffLinear(100 * time.Millisecond),
+		hedgeDelay:    100 * time.Millisecond,
+		maxDrainBytes: 4096,
 	}
 )
 
 type options struct {
-	decider     RequestRetryDeciderFunc
-	discarder   ResponseDiscarderFunc
-	maxRetry    uint
-	backoffFunc BackoffFunc
+	decider              RequestRetryDeciderFunc
+	discarder            ResponseDiscarderFunc
+	maxRetry             uint
+	backoffFunc          BackoffFunc
+	retryAfterMax        time.Duration
+	retryTooManyRequests bool
+	budget               *Budget
+	hedgeDelay           time.Duration
+	hedgePercentile      float64
+	perRetryTimeout      time.Duration
+	maxDrainBytes        int64
 }
 
 func evaluateOptions(opts []Option) *options {
@@ -44,9 +53,8 @@ type ResponseDiscarderFunc func(resp *http.Response) bool
 // BackoffFunc denotes a family of functions that controll the backoff duration between call retries.
 //
 // They are called with an identifier of the attempt, and should return a time the system client should
-// hold off for. If the time returned is longer than the `context.Context.Deadline` of the request
-// the deadline of the request takes precedence and the wait will be interrupted before proceeding
-// with the next iteration.
+// hold off for. If the time returned would last past the `context.Context.Deadline` of the request, the
+// remaining attempts are skipped and the result of the last attempt is returned straight away.
 type BackoffFunc func(attempt uint) time.Duration
 
 // WithMax sets the maximum number of retries on this call, or this interceptor.
@@ -56,6 +64,17 @@ func WithMax(maxRetries uint) Option {
 	}
 }
 
+// WithPerRetryTimeout sets a timeout for each of the attempts of a request.
+//
+// Each attempt gets its own child of the request's `context.Context`, so that a single hung attempt doesn't use up the
+// whole deadline of the request. An attempt that times out is retried like any other error, unless the request's own
+// context is done. A value of 0 (default) means attempts are only limited by the request's context.
+func WithPerRetryTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.perRetryTimeout = timeout
+	}
+}
+
 // WithBackoff sets the `BackoffFunc `used to control time between retries.
 func WithBackoff(bf BackoffFunc) Option {
 	return func(o *options) {
@@ -63,6 +82,64 @@ func WithBackoff(bf BackoffFunc) Option {
 	}
 }
 
+// WithRetryAfter makes the retries honour the `Retry-After` header of discarded responses.
+//
+// Both delta-seconds and HTTP-date values are understood. If a discarded response carries a valid `Retry-After`, the
+// next attempt waits for as long as the header says instead of the `BackoffFunc` duration, but never longer than
+// `maxWait`. Responses without the header (or with a malformed one) fall back to the `BackoffFunc`.
+func WithRetryAfter(maxWait time.Duration) Option {
+	return func(o *options) {
+		o.retryAfterMax = maxWait
+	}
+}
+
+// WithRetryOnTooManyRequests makes responses with the 429 (Too Many Requests) status code retriable.
+//
+// This is on top of whatever the `ResponseDiscarderFunc` decides. It is best used together with `WithRetryAfter`, as
+// rate limiting servers usually say when it is fine to call them again.
+func WithRetryOnTooManyRequests() Option {
+	return func(o *options) {
+		o.retryTooManyRequests = true
+	}
+}
+
+// WithBudget limits the retries using the given `Budget`.
+//
+// The same Budget can be passed to multiple Tripperware instances, in which case their requests and retries are
+// accounted for together.
+func WithBudget(budget *Budget) Option {
+	return func(o *options) {
+		o.budget = budget
+	}
+}
+
+// WithHedgeDelay sets how long the `HedgingTripperware` waits for a response before sending a second request.
+func WithHedgeDelay(delay time.Duration) Option {
+	return func(o *options) {
+		o.hedgeDelay = delay
+	}
+}
+
+// WithHedgeLatencyPercentile makes the `HedgingTripperware` send the second request once the first one takes longer
+// than the given percentile (e.g. 95.0) of recently observed latencies.
+//
+// Until enough latencies are observed, the `WithHedgeDelay` value is used.
+func WithHedgeLatencyPercentile(percentile float64) Option {
+	return func(o *options) {
+		o.hedgePercentile = percentile
+	}
+}
+
+// WithMaxDrainBytes sets how much of the body of a discarded response is read before it is closed.
+//
+// Reading the body to the end allows the connection to be reused for the next attempt, but there's no point reading
+// large bodies that are thrown away. Bodies are drained up to this many bytes (4KB by default) and then closed.
+func WithMaxDrainBytes(maxBytes int64) Option {
+	return func(o *options) {
+		o.maxDrainBytes = maxBytes
+	}
+}
+
 // WithDecider is a function that allows users to customize the logic that decides whether a request is retriable.
 func WithDecider(f RequestRetryDeciderFunc) Option {
 	return func(o *options) {
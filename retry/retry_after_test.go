@@ -0,0 +1,39 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_retry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	for _, tcase := range []struct {
+		header   string
+		expected time.Duration
+		ok       bool
+	}{
+		{header: "", ok: false},
+		{header: "120", expected: 2 * time.Minute, ok: true},
+		{header: " 0 ", expected: 0, ok: true},
+		{header: "-3", ok: false},
+		{header: "99999999999999999", expected: maxDuration, ok: true},
+		{header: "Thu, 01 Jun 2017 12:00:30 GMT", expected: 30 * time.Second, ok: true},
+		{header: "Thursday, 01-Jun-17 12:01:00 GMT", expected: time.Minute, ok: true},
+		{header: "Thu, 01 Jun 2017 11:00:00 GMT", expected: 0, ok: true},
+		{header: "soon", ok: false},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if tcase.header != "" {
+			resp.Header.Set("Retry-After", tcase.header)
+		}
+		wait, ok := retryAfter(resp, now)
+		assert.Equal(t, tcase.ok, ok, "header %q parsed incorrectly", tcase.header)
+		assert.Equal(t, tcase.expected, wait, "header %q has the wrong wait", tcase.header)
+	}
+}
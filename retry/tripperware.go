@@ -5,11 +5,18 @@ package http_retry
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+var (
+	errBackoffPastDeadline = errors.New("backoff exceeds the deadline of the request")
+	errBudgetExhausted     = errors.New("retry budget exhausted")
 )
 
 // Tripperware is client side HTTP ware that retries the requests.
@@ -18,6 +25,20 @@ import (
 // customized using With* parameter options.
 //
 // Requests that have `http_retry.Enable` set on them will always be retried.
+//
+// Responses with a 429 status code are retried only if `WithRetryOnTooManyRequests` is used, and `Retry-After` headers
+// are only honoured if `WithRetryAfter` is used.
+//
+// If a `Budget` is used (see `WithBudget`) and it doesn't allow for another retry, the last response is returned, or
+// a `*BudgetExhaustedError` if the last attempt failed with an error. Either way the request is tagged with
+// `TagForBudgetExhausted`.
+//
+// Each attempt can be given its own timeout using `WithPerRetryTimeout`. The attempt number and the reasons for
+// discarding previous attempts are available through `ExtractAttempts` on the request of the returned response, and
+// the number of attempts is set as the `TagForAttempts` outbound tag.
+//
+// If the request's `context.Context` has a deadline, attempts that would start after it are skipped, and the last
+// response (or error) is returned instead of waiting for the deadline to pass.
 func Tripperware(opts ...Option) httpwares.Tripperware {
 	return func(next http.RoundTripper) http.RoundTripper {
 		o := evaluateOptions(opts)
@@ -32,27 +53,62 @@ func Tripperware(opts ...Option) httpwares.Tripperware {
 				// body data.
 				return next.RoundTrip(req)
 			}
+			if o.budget != nil {
+				o.budget.deposit() // only requests that could be retried count towards the budget
+			}
 			var err error
 			var lastResp *http.Response
+			lastCancel := context.CancelFunc(func() {})
+			var discarded []error
 			for attempt := uint(0); attempt < o.maxRetry; attempt++ {
-				thisReq := req.WithContext(req.Context()) // make a copy.
+				if waitErr := waitRetryBackoff(attempt, lastResp, req.Context(), o); waitErr == errBackoffPastDeadline {
+					break // the next attempt can't finish in time, return what we've got so far
+				} else if waitErr == errBudgetExhausted {
+					http_ctxtags.ExtractOutbound(req).Set(TagForBudgetExhausted, true)
+					if err != nil {
+						err = &BudgetExhaustedError{Err: err}
+					}
+					break
+				} else if waitErr != nil {
+					if lastResp != nil {
+						discardResponse(lastResp, o.maxDrainBytes)
+					}
+					lastCancel()
+					return nil, waitErr // context errors from req.Context()
+				}
+				if attempt > 0 {
+					discarded = append(discarded, attemptError(lastResp, err))
+					if lastResp != nil {
+						discardResponse(lastResp, o.maxDrainBytes) // release the connection for reuse
+					}
+				}
+				lastCancel()
+				var ctx context.Context
+				ctx, lastCancel = attemptContext(req.Context(), o)
+				ctx = context.WithValue(ctx, ctxAttempts, &Attempts{Count: attempt + 1, Errors: append([]error(nil), discarded...)})
+				http_ctxtags.ExtractOutbound(req).Set(TagForAttempts, attempt+1)
+				thisReq := req.WithContext(ctx) // make a copy.
 				thisReq.Body, err = getBodyFn()
 				if err != nil {
+					lastCancel()
 					return nil, fmt.Errorf("failed reading body for retry: %v", err)
 				}
-				if err := waitRetryBackoff(attempt, req.Context(), o); err != nil {
-					return nil, err // context errors from req.Context()
-				}
 				lastResp, err = next.RoundTrip(thisReq)
-				if isContextError(err) {
-					break // do not retry context errors
-				} else if err == nil && !o.discarder(lastResp) {
+				if err == nil && !o.shouldDiscard(lastResp) {
 					break // do not retry responses that the discarder tells us we should not discard
+				} else if req.Context().Err() != nil {
+					break // do not retry once the request's context is done
 				}
 			}
 			if lastResp != nil {
+				if lastResp.Body != nil {
+					lastResp.Body = &cancelOnCloseBody{ReadCloser: lastResp.Body, cancel: lastCancel}
+				} else {
+					lastCancel()
+				}
 				return lastResp, err
 			} else if err != nil {
+				lastCancel()
 				return nil, err
 			}
 			return nil, fmt.Errorf("maximum retry budget of %d reached", o.maxRetry)
@@ -60,10 +116,24 @@ func Tripperware(opts ...Option) httpwares.Tripperware {
 	}
 }
 
-func waitRetryBackoff(attempt uint, parentCtx context.Context, opt *options) error {
+func waitRetryBackoff(attempt uint, lastResp *http.Response, parentCtx context.Context, opt *options) error {
 	var waitTime time.Duration = 0
 	if attempt > 0 {
 		waitTime = opt.backoffFunc(attempt)
+		if opt.retryAfterMax > 0 && lastResp != nil {
+			if wait, ok := retryAfter(lastResp, time.Now()); ok {
+				waitTime = wait
+				if waitTime > opt.retryAfterMax {
+					waitTime = opt.retryAfterMax
+				}
+			}
+		}
+	}
+	if deadline, ok := parentCtx.Deadline(); ok && attempt > 0 && !time.Now().Add(waitTime).Before(deadline) {
+		return errBackoffPastDeadline
+	}
+	if opt.budget != nil && attempt > 0 && !opt.budget.withdraw() {
+		return errBudgetExhausted
 	}
 	if waitTime > 0 {
 		select {
@@ -75,6 +145,17 @@ func waitRetryBackoff(attempt uint, parentCtx context.Context, opt *options) err
 	return nil
 }
 
-func isContextError(err error) bool {
-	return err == context.DeadlineExceeded || err == context.Canceled
+func (o *options) shouldDiscard(resp *http.Response) bool {
+	if o.retryTooManyRequests && resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return o.discarder(resp)
+}
+
+// attemptContext returns the context for a single attempt, limited by `WithPerRetryTimeout` if it is set.
+func attemptContext(parentCtx context.Context, opt *options) (context.Context, context.CancelFunc) {
+	if opt.perRetryTimeout > 0 {
+		return context.WithTimeout(parentCtx, opt.perRetryTimeout)
+	}
+	return parentCtx, func() {}
 }
@@ -0,0 +1,15 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_signing
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// contentDigest returns the value of the `Content-Digest` header (RFC 9530) of the body.
+func contentDigest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha-256=:" + base64.StdEncoding.EncodeToString(sum[:]) + ":"
+}
@@ -0,0 +1,24 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_signing` signs HTTP requests and verifies their signatures, following HTTP Message Signatures (RFC 9421).
+
+Signatures cover the method, path and query of requests, a `Content-Digest` (RFC 9530) of their body, and a
+configurable set of headers (see `WithHeaders`). They are sent in the `Signature-Input` and `Signature` headers, along
+with the ID of the key used, the time of signing and a random nonce.
+
+Client-side Signing
+
+The Tripperware signs requests with the given key. Bodies are digested using their `GetBody` method if they have one,
+and are buffered otherwise, like for retries of `http_retry`.
+
+Server-side Verification
+
+The Middleware looks up the key of the signature by its ID in a `KeyStore`, checks that the signature covers all it
+should and was made recently (see `WithMaxSkew`), and that its nonce wasn't seen before (see `NonceCache`), so that
+signed requests can't be replayed. Requests failing verification are rejected with a 401 (Unauthorized) status code.
+
+Keys are `Algorithm`s, of which HMAC-SHA256 is built in (`HMACSHA256`).
+*/
+package http_signing
@@ -0,0 +1,144 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_signing_test
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/signing"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const mischiefHeader = "x-test-mischief"
+
+var secret = []byte("very secret")
+
+// echoHandler reports back the body of the request and the key that signed it.
+func echoHandler(resp http.ResponseWriter, req *http.Request) {
+	if keyID, ok := http_ctxtags.ExtractInbound(req).Values()[http_signing.TagForKeyID].(string); ok {
+		resp.Header().Set("x-test-key-id", keyID)
+	}
+	body, _ := ioutil.ReadAll(req.Body)
+	resp.WriteHeader(http.StatusOK)
+	resp.Write(body)
+}
+
+// mischief messes with signed requests, as asked by their mischief header.
+type mischief struct {
+	mu         sync.Mutex
+	lastSigned *http.Request
+}
+
+func (m *mischief) tripperware(next http.RoundTripper) http.RoundTripper {
+	return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		switch req.Header.Get(mischiefHeader) {
+		case "tamper-body":
+			req.Body = ioutil.NopCloser(strings.NewReader("tampered"))
+		case "tamper-query":
+			req.URL.RawQuery = "admin=true"
+		case "tamper-header":
+			req.Header.Set("X-Custom", "tampered")
+		case "unsigned":
+			req.Header.Del("Signature")
+			req.Header.Del("Signature-Input")
+		case "replay":
+			req.Header = m.lastSigned.Header
+		}
+		m.lastSigned = req
+		return next.RoundTrip(req)
+	})
+}
+
+func TestSigningSuite(t *testing.T) {
+	m := &mischief{}
+	s := &SigningSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: http.HandlerFunc(echoHandler),
+			ServerMiddleware: []httpwares.Middleware{
+				http_ctxtags.Middleware("my_group"),
+				http_signing.Middleware(
+					http_signing.StaticKeys{"key-1": http_signing.HMACSHA256(secret)},
+					http_signing.WithHeaders("X-Custom"),
+				),
+			},
+			ClientTripperware: []httpwares.Tripperware{
+				http_signing.Tripperware("key-1", http_signing.HMACSHA256(secret), http_signing.WithHeaders("X-Custom")),
+				m.tripperware,
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type SigningSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *SigningSuite) call(method string, body string, headers ...string) (*http.Response, string) {
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+	req, _ := http.NewRequest(method, "https://something.local/someurl?q=1", reqBody)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	respBody, _ := ioutil.ReadAll(resp.Body)
+	return resp, string(respBody)
+}
+
+func (s *SigningSuite) TestSignedRequestsAreAccepted() {
+	resp, _ := s.call("GET", "")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), "key-1", resp.Header.Get("x-test-key-id"))
+
+	resp, body := s.call("POST", "some body", "X-Custom", "value")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	assert.Equal(s.T(), "some body", body, "the body must still be readable by the handler")
+}
+
+func (s *SigningSuite) TestTamperedRequestsAreRejected() {
+	for _, m := range []string{"tamper-body", "tamper-query", "tamper-header", "unsigned"} {
+		resp, _ := s.call("POST", "some body", mischiefHeader, m, "X-Custom", "value")
+		assert.Equal(s.T(), http.StatusUnauthorized, resp.StatusCode, "%v must be rejected", m)
+		assert.Empty(s.T(), resp.Header.Get("x-test-key-id"), "%v must not reach the handler", m)
+	}
+}
+
+func (s *SigningSuite) TestUncoveredHeadersAreRejected() {
+	resp, _ := s.call("GET", "", mischiefHeader, "tamper-header")
+	assert.Equal(s.T(), http.StatusUnauthorized, resp.StatusCode, "headers that must be covered can't be added")
+}
+
+func (s *SigningSuite) TestReplayedRequestsAreRejected() {
+	resp, _ := s.call("GET", "")
+	require.Equal(s.T(), http.StatusOK, resp.StatusCode)
+	resp, _ = s.call("GET", "", mischiefHeader, "replay")
+	assert.Equal(s.T(), http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestUnknownKeysAreRejected(t *testing.T) {
+	server := httptest.NewServer(http_signing.Middleware(http_signing.StaticKeys{})(http.HandlerFunc(echoHandler)))
+	defer server.Close()
+	client := httpwares.WrapClient(&http.Client{}, http_signing.Tripperware("key-1", http_signing.HMACSHA256(secret)))
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err, "call shouldn't fail")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+}
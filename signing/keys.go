@@ -0,0 +1,65 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_signing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+)
+
+// Algorithm signs and verifies signatures with a key.
+type Algorithm interface {
+	// Name is the name of the algorithm in the HTTP Signature Algorithms registry, e.g. "hmac-sha256".
+	Name() string
+	// Sign returns the signature of the data.
+	Sign(data []byte) ([]byte, error)
+	// Verify checks the signature of the data.
+	Verify(data []byte, signature []byte) error
+}
+
+// HMACSHA256 returns the "hmac-sha256" Algorithm with the shared secret.
+func HMACSHA256(secret []byte) Algorithm {
+	return &hmacSHA256{secret}
+}
+
+type hmacSHA256 struct {
+	secret []byte
+}
+
+func (a *hmacSHA256) Name() string {
+	return "hmac-sha256"
+}
+
+func (a *hmacSHA256) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+func (a *hmacSHA256) Verify(data []byte, signature []byte) error {
+	expected, _ := a.Sign(data)
+	if !hmac.Equal(expected, signature) {
+		return errors.New("signing: invalid signature")
+	}
+	return nil
+}
+
+// KeyStore returns the key with the given ID.
+type KeyStore interface {
+	Key(ctx context.Context, keyID string) (Algorithm, error)
+}
+
+// StaticKeys is a KeyStore with a fixed set of keys, by key ID.
+type StaticKeys map[string]Algorithm
+
+func (k StaticKeys) Key(ctx context.Context, keyID string) (Algorithm, error) {
+	key, ok := k[keyID]
+	if !ok {
+		return nil, fmt.Errorf("signing: unknown key %q", keyID)
+	}
+	return key, nil
+}
@@ -0,0 +1,217 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_signing
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+var errMalformedSignature = errors.New("signing: malformed signature headers")
+
+// signatureParams are the covered components and parameters of a signature, as in the `Signature-Input` header.
+type signatureParams struct {
+	components []string
+	created    int64
+	nonce      string
+	keyID      string
+	algorithm  string
+}
+
+// String serializes the params as a structured field inner list, which is both the value of the `Signature-Input`
+// header and of the `@signature-params` component.
+func (p *signatureParams) String() string {
+	quoted := make([]string, len(p.components))
+	for i, c := range p.components {
+		quoted[i] = quoteString(c)
+	}
+	out := "(" + strings.Join(quoted, " ") + ");created=" + strconv.FormatInt(p.created, 10)
+	if p.nonce != "" {
+		out += ";nonce=" + quoteString(p.nonce)
+	}
+	if p.keyID != "" {
+		out += ";keyid=" + quoteString(p.keyID)
+	}
+	if p.algorithm != "" {
+		out += ";alg=" + quoteString(p.algorithm)
+	}
+	return out
+}
+
+func (p *signatureParams) covers(component string) bool {
+	for _, c := range p.components {
+		if c == component {
+			return true
+		}
+	}
+	return false
+}
+
+// signatureBase returns the data that is signed for the request.
+func signatureBase(req *http.Request, params *signatureParams, serializedParams string) ([]byte, error) {
+	lines := []string{}
+	for _, c := range params.components {
+		value, err := componentValue(req, c)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, quoteString(c)+": "+value)
+	}
+	lines = append(lines, `"@signature-params": `+serializedParams)
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+func componentValue(req *http.Request, component string) (string, error) {
+	switch component {
+	case "@method":
+		return req.Method, nil
+	case "@path":
+		if path := req.URL.EscapedPath(); path != "" {
+			return path, nil
+		}
+		return "/", nil
+	case "@query":
+		return "?" + req.URL.RawQuery, nil
+	case "@authority":
+		if req.Host != "" {
+			return strings.ToLower(req.Host), nil
+		}
+		return strings.ToLower(req.URL.Host), nil
+	}
+	if strings.HasPrefix(component, "@") {
+		return "", fmt.Errorf("signing: unsupported component %q", component)
+	}
+	values, ok := req.Header[http.CanonicalHeaderKey(component)]
+	if !ok {
+		return "", fmt.Errorf("signing: covered header %q missing", component)
+	}
+	trimmed := make([]string, len(values))
+	for i, v := range values {
+		trimmed[i] = strings.TrimSpace(v)
+	}
+	return strings.Join(trimmed, ", "), nil
+}
+
+// dictionaryMember returns the raw value of the member with the label of a structured field dictionary, such as the
+// `Signature-Input` and `Signature` headers.
+func dictionaryMember(headerValues []string, label string) (string, bool) {
+	for _, member := range splitDictionary(strings.Join(headerValues, ", ")) {
+		eq := strings.Index(member, "=")
+		if eq > 0 && strings.TrimSpace(member[:eq]) == label {
+			return strings.TrimSpace(member[eq+1:]), true
+		}
+	}
+	return "", false
+}
+
+// splitDictionary splits a structured field dictionary into its members, minding commas within strings.
+func splitDictionary(value string) []string {
+	members := []string{}
+	inString, escaped, start := false, false, 0
+	for i := 0; i < len(value); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && value[i] == '\\':
+			escaped = true
+		case value[i] == '"':
+			inString = !inString
+		case !inString && value[i] == ',':
+			members = append(members, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(members, value[start:])
+}
+
+// parseSignatureParams parses the value of a member of the `Signature-Input` header.
+func parseSignatureParams(value string) (*signatureParams, error) {
+	if !strings.HasPrefix(value, "(") {
+		return nil, errMalformedSignature
+	}
+	p := &signatureParams{}
+	rest := value[1:]
+	for {
+		rest = strings.TrimLeft(rest, " ")
+		if strings.HasPrefix(rest, ")") {
+			rest = rest[1:]
+			break
+		}
+		s, remaining, err := parseString(rest)
+		if err != nil {
+			return nil, err
+		}
+		p.components = append(p.components, s)
+		rest = remaining
+	}
+	for rest != "" {
+		if rest[0] != ';' {
+			return nil, errMalformedSignature
+		}
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			return nil, errMalformedSignature
+		}
+		name := strings.TrimSpace(rest[1:eq])
+		rest = rest[eq+1:]
+		var s string
+		var err error
+		if strings.HasPrefix(rest, `"`) {
+			s, rest, err = parseString(rest)
+		} else {
+			end := strings.Index(rest, ";")
+			if end < 0 {
+				end = len(rest)
+			}
+			s, rest = rest[:end], rest[end:]
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch name {
+		case "created":
+			if p.created, err = strconv.ParseInt(s, 10, 64); err != nil {
+				return nil, errMalformedSignature
+			}
+		case "nonce":
+			p.nonce = s
+		case "keyid":
+			p.keyID = s
+		case "alg":
+			p.algorithm = s
+		}
+	}
+	return p, nil
+}
+
+// parseString parses a structured field string at the start of the value, returning it and the rest of the value.
+func parseString(value string) (string, string, error) {
+	if !strings.HasPrefix(value, `"`) {
+		return "", "", errMalformedSignature
+	}
+	out := []byte{}
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if i+1 == len(value) {
+				return "", "", errMalformedSignature
+			}
+			i++
+			out = append(out, value[i])
+		case '"':
+			return string(out), value[i+1:], nil
+		default:
+			out = append(out, value[i])
+		}
+	}
+	return "", "", errMalformedSignature
+}
+
+// quoteString serializes a structured field string.
+func quoteString(value string) string {
+	return `"` + strings.Replace(strings.Replace(value, `\`, `\\`, -1), `"`, `\"`, -1) + `"`
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_signing
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureParamsRoundTrip(t *testing.T) {
+	params := &signatureParams{
+		components: []string{"@method", "@path", "content-digest"},
+		created:    1618884473,
+		nonce:      "abc",
+		keyID:      `key "with" \quotes`,
+		algorithm:  "hmac-sha256",
+	}
+	serialized := params.String()
+	assert.Equal(t, `("@method" "@path" "content-digest");created=1618884473;nonce="abc";keyid="key \"with\" \\quotes";alg="hmac-sha256"`, serialized)
+	parsed, err := parseSignatureParams(serialized)
+	require.NoError(t, err)
+	assert.Equal(t, params, parsed)
+}
+
+func TestParseSignatureParamsRejectsMalformed(t *testing.T) {
+	for _, value := range []string{
+		``,
+		`"@method"`,
+		`("@method"`,
+		`("@method);created=1`,
+		`("@method");created=yesterday`,
+		`("@method")created=1`,
+	} {
+		_, err := parseSignatureParams(value)
+		assert.Error(t, err, "value %q must be rejected", value)
+	}
+}
+
+func TestDictionaryMember(t *testing.T) {
+	header := []string{`sig0=("@method");keyid="a,b"`, `sig1=("@path");keyid="c"`}
+	value, ok := dictionaryMember(header, "sig1")
+	require.True(t, ok)
+	assert.Equal(t, `("@path");keyid="c"`, value)
+	value, ok = dictionaryMember(header, "sig0")
+	require.True(t, ok)
+	assert.Equal(t, `("@method");keyid="a,b"`, value, "commas within strings must not split members")
+	_, ok = dictionaryMember(header, "sig2")
+	assert.False(t, ok)
+}
+
+// TestSignatureBaseMatchesSpecification uses the HMAC-SHA256 example of RFC 9421, appendix B.2.5.
+func TestSignatureBaseMatchesSpecification(t *testing.T) {
+	req, _ := http.NewRequest("POST", "http://example.com/foo?param=Value&Pet=dog", nil)
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+	input := `("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`
+	params, err := parseSignatureParams(input)
+	require.NoError(t, err)
+
+	base, err := signatureBase(req, params, input)
+	require.NoError(t, err)
+	assert.Equal(t, `"date": Tue, 20 Apr 2021 02:07:55 GMT
+"@authority": example.com
+"content-type": application/json
+"@signature-params": ("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`, string(base))
+
+	secret, _ := base64.StdEncoding.DecodeString("uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ==")
+	signature, _ := base64.StdEncoding.DecodeString("pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=")
+	assert.NoError(t, HMACSHA256(secret).Verify(base, signature))
+}
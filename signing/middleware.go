@@ -0,0 +1,144 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_signing
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/logging/logrus/ctxlogrus"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/sirupsen/logrus"
+)
+
+// TagForKeyID is the inbound tag set to the ID of the key that signed the request.
+const TagForKeyID = "signing.key_id"
+
+// Middleware returns a http.Handler middleware that verifies the signatures of requests with keys from the KeyStore.
+//
+// Requests that aren't signed, or whose signature isn't valid, are rejected with a 401 (Unauthorized) status code.
+// The reason is added to the request's log statement.
+func Middleware(keys KeyStore, opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	if o.nonceCache == nil {
+		o.nonceCache = NewMemoryNonceCache()
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			keyID, err := verify(req, keys, o)
+			if err != nil {
+				ctxlogrus.AddFields(req.Context(), logrus.Fields{"signing.error": err.Error()})
+				http.Error(resp, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			http_ctxtags.ExtractInbound(req).Set(TagForKeyID, keyID)
+			next.ServeHTTP(resp, req)
+		})
+	}
+}
+
+// verify checks the signature of the request, returning the ID of the key that made it.
+func verify(req *http.Request, keys KeyStore, o *options) (string, error) {
+	input, ok := dictionaryMember(req.Header["Signature-Input"], o.label)
+	if !ok {
+		return "", errors.New("signing: request not signed")
+	}
+	params, err := parseSignatureParams(input)
+	if err != nil {
+		return "", err
+	}
+	signature, err := signatureValue(req.Header["Signature"], o.label)
+	if err != nil {
+		return "", err
+	}
+	if err := checkCoverage(req, params, o); err != nil {
+		return "", err
+	}
+	created := time.Unix(params.created, 0)
+	if now := o.clock(); created.Before(now.Add(-o.maxSkew)) || created.After(now.Add(o.maxSkew)) {
+		return "", errors.New("signing: signature too old or too new")
+	}
+	key, err := keys.Key(req.Context(), params.keyID)
+	if err != nil {
+		return "", err
+	}
+	if params.algorithm != "" && params.algorithm != key.Name() {
+		return "", fmt.Errorf("signing: key %q is for %v, not %v", params.keyID, key.Name(), params.algorithm)
+	}
+	base, err := signatureBase(req, params, input)
+	if err != nil {
+		return "", err
+	}
+	if err := key.Verify(base, signature); err != nil {
+		return "", err
+	}
+	if params.covers("content-digest") {
+		if err := checkDigest(req, o.maxBodySize); err != nil {
+			return "", err
+		}
+	}
+	// Checked last, so that only valid signatures use up their nonce.
+	if o.nonceCache.Seen(params.keyID, params.nonce, created.Add(o.maxSkew)) {
+		return "", errors.New("signing: replayed signature")
+	}
+	return params.keyID, nil
+}
+
+func signatureValue(headerValues []string, label string) ([]byte, error) {
+	value, ok := dictionaryMember(headerValues, label)
+	if !ok || len(value) < 2 || value[0] != ':' || value[len(value)-1] != ':' {
+		return nil, errMalformedSignature
+	}
+	signature, err := base64.StdEncoding.DecodeString(value[1 : len(value)-1])
+	if err != nil {
+		return nil, errMalformedSignature
+	}
+	return signature, nil
+}
+
+// checkCoverage checks that the signature covers all the components it should, and has a nonce.
+func checkCoverage(req *http.Request, params *signatureParams, o *options) error {
+	required := []string{"@method", "@path", "@query"}
+	if req.ContentLength != 0 {
+		required = append(required, "content-digest")
+	}
+	for _, c := range append(required, o.coveredHeaders(req.Header)...) {
+		if !params.covers(c) {
+			return fmt.Errorf("signing: signature doesn't cover %q", c)
+		}
+	}
+	if params.nonce == "" || params.keyID == "" {
+		return errors.New("signing: signature without a nonce or key ID")
+	}
+	return nil
+}
+
+// checkDigest checks the `Content-Digest` of the request against its body, which it replaces with one that can still
+// be read by handlers.
+func checkDigest(req *http.Request, maxBodySize int64) error {
+	expected, ok := dictionaryMember(req.Header["Content-Digest"], "sha-256")
+	if !ok {
+		return errors.New("signing: missing sha-256 content digest")
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(req.Body, maxBodySize+1))
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > maxBodySize {
+		return errors.New("signing: body too large to digest")
+	}
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if "sha-256="+expected != contentDigest(data) {
+		return errors.New("signing: content digest doesn't match the body")
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_signing
+
+import (
+	"sync"
+	"time"
+)
+
+// NonceCache remembers the nonces of signatures, so that requests can't be replayed.
+type NonceCache interface {
+	// Seen records the nonce of the key until the expiry, and reports whether it was already recorded.
+	Seen(keyID string, nonce string, expiry time.Time) bool
+}
+
+// NewMemoryNonceCache returns a NonceCache keeping the nonces in memory.
+//
+// Servers behind a load balancer should share a NonceCache instead.
+func NewMemoryNonceCache() NonceCache {
+	return &memoryNonceCache{nonces: make(map[string]time.Time), clock: time.Now}
+}
+
+type memoryNonceCache struct {
+	mu          sync.Mutex
+	nonces      map[string]time.Time
+	nextCleanup time.Time
+	clock       func() time.Time
+}
+
+func (c *memoryNonceCache) Seen(keyID string, nonce string, expiry time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.clock()
+	if now.After(c.nextCleanup) {
+		for k, e := range c.nonces {
+			if now.After(e) {
+				delete(c.nonces, k)
+			}
+		}
+		c.nextCleanup = now.Add(time.Minute)
+	}
+	key := keyID + "\n" + nonce
+	if e, ok := c.nonces[key]; ok && !now.After(e) {
+		return true
+	}
+	c.nonces[key] = expiry
+	return false
+}
@@ -0,0 +1,98 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_signing
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	defaultOptions = &options{
+		label:       "sig1",
+		headers:     nil,
+		maxSkew:     5 * time.Minute,
+		nonceCache:  nil,
+		maxBodySize: 1 << 20,
+		clock:       time.Now,
+	}
+)
+
+type options struct {
+	label       string
+	headers     []string
+	maxSkew     time.Duration
+	nonceCache  NonceCache
+	maxBodySize int64
+	clock       func() time.Time
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithLabel sets the label of the signature in the `Signature-Input` and `Signature` headers. The default is "sig1".
+func WithLabel(label string) Option {
+	return func(o *options) {
+		o.label = label
+	}
+}
+
+// WithHeaders sets the headers that signatures cover, in addition to the method, path, query and body digest.
+//
+// The Tripperware covers those that requests have, and the Middleware rejects requests with any of them not covered.
+func WithHeaders(names ...string) Option {
+	return func(o *options) {
+		o.headers = nil
+		for _, n := range names {
+			o.headers = append(o.headers, strings.ToLower(n))
+		}
+	}
+}
+
+// WithMaxSkew sets how long before or after its signing a request is accepted by the Middleware. The default is five
+// minutes.
+//
+// Nonces are remembered for this long on both sides of the time of signing.
+func WithMaxSkew(skew time.Duration) Option {
+	return func(o *options) {
+		o.maxSkew = skew
+	}
+}
+
+// WithNonceCache sets the NonceCache used by the Middleware to reject replayed requests.
+//
+// By default each Middleware uses its own `NewMemoryNonceCache`.
+func WithNonceCache(cache NonceCache) Option {
+	return func(o *options) {
+		o.nonceCache = cache
+	}
+}
+
+// WithMaxBodySize sets the size in bytes of the largest request body the Middleware digests. The default is 1MB.
+//
+// Requests with larger bodies are rejected.
+func WithMaxBodySize(bytes int64) Option {
+	return func(o *options) {
+		o.maxBodySize = bytes
+	}
+}
+
+func (o *options) coveredHeaders(h http.Header) []string {
+	covered := []string{}
+	for _, name := range o.headers {
+		if _, ok := h[http.CanonicalHeaderKey(name)]; ok {
+			covered = append(covered, name)
+		}
+	}
+	return covered
+}
@@ -0,0 +1,81 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_signing
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/internal/header"
+	"github.com/improbable-eng/go-httpwares/internal/requestbody"
+)
+
+// Tripperware returns a new client-side ware that signs requests with the key of the given ID.
+//
+// The signature covers the headers set by tripperwares before this one in the chain, so it should be placed last.
+func Tripperware(keyID string, key Algorithm, opts ...Option) httpwares.Tripperware {
+	o := evaluateOptions(opts)
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpwares.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			newReq := http_header.CloneRequestWithHeader(req)
+			components := []string{"@method", "@path", "@query"}
+			if req.Body != nil {
+				digest, err := digestBody(req, newReq)
+				if err != nil {
+					return nil, err
+				}
+				newReq.Header.Set("Content-Digest", digest)
+				components = append(components, "content-digest")
+			}
+			nonce := make([]byte, 16)
+			if _, err := rand.Read(nonce); err != nil {
+				return nil, err
+			}
+			params := &signatureParams{
+				components: append(components, o.coveredHeaders(newReq.Header)...),
+				created:    o.clock().Unix(),
+				nonce:      base64.RawURLEncoding.EncodeToString(nonce),
+				keyID:      keyID,
+				algorithm:  key.Name(),
+			}
+			serialized := params.String()
+			base, err := signatureBase(newReq, params, serialized)
+			if err != nil {
+				return nil, err
+			}
+			signature, err := key.Sign(base)
+			if err != nil {
+				return nil, err
+			}
+			newReq.Header.Set("Signature-Input", o.label+"="+serialized)
+			newReq.Header.Set("Signature", o.label+"=:"+base64.StdEncoding.EncodeToString(signature)+":")
+			return next.RoundTrip(newReq)
+		})
+	}
+}
+
+// digestBody returns the content digest of the body of the request, setting a body that can still be read on the copy
+// of it that is sent.
+func digestBody(req *http.Request, newReq *http.Request) (string, error) {
+	getBody := http_requestbody.GetBody(req)
+	body, err := getBody()
+	if err != nil {
+		return "", err
+	}
+	var data []byte
+	if body != nil {
+		data, err = ioutil.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	if newReq.Body, err = getBody(); err != nil {
+		return "", err
+	}
+	return contentDigest(data), nil
+}
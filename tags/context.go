@@ -78,6 +78,21 @@ func ExtractOutboundFromCtx(ctx context.Context) *Tags {
 	return t
 }
 
+// CopyOutboundToCtx returns a context holding a copy of the outbound Tags of ctx, along with the copy.
+// It is meant for requests sent concurrently on behalf of the same call (e.g. hedged requests), as Tags are not thread
+// safe. If ctx has no outbound Tags, it is returned as it is, with a no-op Tag storage.
+func CopyOutboundToCtx(ctx context.Context) (context.Context, *Tags) {
+	t, ok := ctx.Value(clientsideMarker).(*Tags)
+	if !ok {
+		return ctx, &Tags{values: make(map[string]interface{})}
+	}
+	clone := &Tags{values: make(map[string]interface{}, len(t.values))}
+	for k, v := range t.values {
+		clone.values[k] = v
+	}
+	return context.WithValue(ctx, clientsideMarker, clone), clone
+}
+
 func setOutboundInCtx(ctx context.Context, tags *Tags) context.Context {
 	t, ok := ctx.Value(clientsideMarker).(*Tags)
 	if ok && t == tags { // points to same variable, no point setting.
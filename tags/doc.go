@@ -27,6 +27,12 @@ easy organisation of HTTP endpoints for logging and monitoring purposes.
 
 See `TagFor*` consts below.
 
+TLS Peer Tags
+
+With `WithTLSPeerTags` the Middleware also tags requests made over TLS with the version and cipher suite of the
+connection. Peers that presented a client certificate verified by the server (mutual TLS) are identified by its subject,
+issuer, DNS names, URIs and SPIFFE ID (e.g. "spiffe://example.org/ns/prod/sa/frontend").
+
 Custom Tags
 
 You can provide a `WithTagExtractor` function that will populate tags server-side and client-side. Each `http.Request`
@@ -1,7 +1,9 @@
 package http_ctxtags_test
 
 import (
+	"crypto/tls"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi"
@@ -85,3 +87,72 @@ func (s *TaggingSuite) TestCustomTagsAreBeingUsed() {
 	assert.NotEmpty(s.T(), requestTags, "request leaving client has tags from client tripperware")
 	assert.Equal(s.T(), "MyServiceCapitalised", requestTags.Values()["http.call.service"], "request should have serviceName updated by TagRequest")
 }
+
+// tagsReportingHandler reports back all inbound tags as x-tag-<name> headers.
+func tagsReportingHandler(resp http.ResponseWriter, req *http.Request) {
+	for k, v := range http_ctxtags.ExtractInbound(req).Values() {
+		if str, ok := v.(string); ok {
+			resp.Header().Set("x-tag-"+k, str)
+		}
+	}
+	resp.WriteHeader(http.StatusOK)
+}
+
+func TestTLSPeerTaggingSuite(t *testing.T) {
+	ca, err := httpwares_testing.NewCertificateAuthority("test-ca")
+	require.NoError(t, err, "creating a CA must not fail")
+	cert, err := ca.IssueClientCertificate(
+		"frontend",
+		[]string{"frontend.example.org"},
+		[]string{"spiffe://example.org/ns/prod/sa/frontend"},
+	)
+	require.NoError(t, err, "issuing a client certificate must not fail")
+	s := &TLSPeerTaggingSuite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: http.HandlerFunc(tagsReportingHandler),
+			ServerMiddleware: []httpwares.Middleware{
+				http_ctxtags.Middleware("someservice", http_ctxtags.WithTLSPeerTags()),
+			},
+			ServerClientCAs:    ca.CertPool(),
+			ClientCertificates: []tls.Certificate{cert},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type TLSPeerTaggingSuite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *TLSPeerTaggingSuite) call(client *http.Client) *http.Response {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	resp, err := client.Do(req)
+	require.NoError(s.T(), err, "call shouldn't fail")
+	require.Equal(s.T(), http.StatusOK, resp.StatusCode, "call should succeed")
+	if resp.TLS == nil {
+		s.T().Skip("TLS peer tags need the server to use TLS")
+	}
+	return resp
+}
+
+func (s *TLSPeerTaggingSuite) TestCertificateTagsAreSet() {
+	resp := s.call(s.NewClient())
+	assert.Equal(s.T(), "frontend", resp.Header.Get("x-tag-"+http_ctxtags.TagForPeerCertSubject))
+	assert.Equal(s.T(), "test-ca", resp.Header.Get("x-tag-"+http_ctxtags.TagForPeerCertIssuer))
+	assert.Equal(s.T(), "frontend.example.org", resp.Header.Get("x-tag-"+http_ctxtags.TagForPeerCertDNSNames))
+	assert.Equal(s.T(), "spiffe://example.org/ns/prod/sa/frontend", resp.Header.Get("x-tag-"+http_ctxtags.TagForPeerCertURIs))
+	assert.Equal(s.T(), "spiffe://example.org/ns/prod/sa/frontend", resp.Header.Get("x-tag-"+http_ctxtags.TagForPeerSpiffeID))
+	assert.True(s.T(), strings.HasPrefix(resp.Header.Get("x-tag-"+http_ctxtags.TagForPeerTLSVersion), "TLS1."), "TLS version must be tagged")
+	assert.NotEmpty(s.T(), resp.Header.Get("x-tag-"+http_ctxtags.TagForPeerTLSCipherSuite), "cipher suite must be tagged")
+}
+
+func (s *TLSPeerTaggingSuite) TestOnlyConnectionTagsAreSetWithoutCertificate() {
+	certs := s.ClientCertificates
+	s.ClientCertificates = nil
+	client := s.NewClient()
+	s.ClientCertificates = certs
+	resp := s.call(client)
+	assert.NotEmpty(s.T(), resp.Header.Get("x-tag-"+http_ctxtags.TagForPeerTLSVersion), "TLS version must be tagged")
+	assert.Empty(s.T(), resp.Header.Get("x-tag-"+http_ctxtags.TagForPeerCertSubject), "no certificate must be tagged")
+	assert.Empty(s.T(), resp.Header.Get("x-tag-"+http_ctxtags.TagForPeerSpiffeID), "no SPIFFE ID must be tagged")
+}
@@ -24,6 +24,9 @@ func Middleware(handlerGroupName string, opts ...Option) httpwares.Middleware {
 				needUpdatingContext = false
 			}
 			defaultRequestTags(t, req)
+			if o.tlsPeerTags {
+				tlsPeerTags(t, req)
+			}
 			for _, extractor := range o.tagExtractors {
 				if output := extractor(req); output != nil {
 					for k, v := range output {
@@ -26,6 +26,7 @@ var (
 		tagExtractors:           []RequestTagExtractorFunc{},
 		serviceName:             "",
 		serviceNameDetectorFunc: DefaultServiceNameDetector,
+		tlsPeerTags:             false,
 	}
 )
 
@@ -33,6 +34,7 @@ type options struct {
 	tagExtractors           []RequestTagExtractorFunc
 	serviceName             string
 	serviceNameDetectorFunc serviceNameDetectorFunc
+	tlsPeerTags             bool
 }
 
 func evaluateOptions(opts []Option) *options {
@@ -58,6 +60,16 @@ func WithTagExtractor(f RequestTagExtractorFunc) Option {
 	}
 }
 
+// WithTLSPeerTags makes the Middleware tag requests over TLS with the version and cipher suite of the connection, and
+// the identity of the peer if it presented a client certificate that was verified.
+//
+// See the `TagForPeer*` consts for the tags set.
+func WithTLSPeerTags() Option {
+	return func(o *options) {
+		o.tlsPeerTags = true
+	}
+}
+
 // WithServiceName is an option for client-side wares that explicitly states the name of the service called.
 //
 // This option takes precedence over the WithServiceNameDetector values.
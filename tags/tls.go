@@ -0,0 +1,55 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_ctxtags
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/improbable-eng/go-httpwares/internal/peercert"
+)
+
+const (
+	// TagForPeerTLSVersion is the ctxtag naming the TLS version of the connection (e.g. "TLS1.2").
+	TagForPeerTLSVersion = "peer.tls.version"
+	// TagForPeerTLSCipherSuite is the ctxtag naming the TLS cipher suite of the connection.
+	TagForPeerTLSCipherSuite = "peer.tls.cipher_suite"
+	// TagForPeerCertSubject is the ctxtag with the common name of the subject of the verified client certificate.
+	TagForPeerCertSubject = "peer.cert.subject"
+	// TagForPeerCertIssuer is the ctxtag with the common name of the issuer of the verified client certificate.
+	TagForPeerCertIssuer = "peer.cert.issuer"
+	// TagForPeerCertDNSNames is the ctxtag with the comma-separated DNS names of the verified client certificate.
+	TagForPeerCertDNSNames = "peer.cert.dns_names"
+	// TagForPeerCertURIs is the ctxtag with the comma-separated URIs of the verified client certificate.
+	TagForPeerCertURIs = "peer.cert.uris"
+	// TagForPeerSpiffeID is the ctxtag with the SPIFFE ID of the verified client certificate.
+	TagForPeerSpiffeID = "peer.spiffe_id"
+)
+
+func tlsPeerTags(t *Tags, req *http.Request) {
+	if req.TLS == nil {
+		return
+	}
+	t.Set(TagForPeerTLSVersion, http_peercert.VersionName(req.TLS.Version))
+	t.Set(TagForPeerTLSCipherSuite, http_peercert.CipherSuiteName(req.TLS.CipherSuite))
+	cert, ok := http_peercert.VerifiedCertificate(req.TLS)
+	if !ok {
+		return
+	}
+	if cert.Subject.CommonName != "" {
+		t.Set(TagForPeerCertSubject, cert.Subject.CommonName)
+	}
+	if cert.Issuer.CommonName != "" {
+		t.Set(TagForPeerCertIssuer, cert.Issuer.CommonName)
+	}
+	if len(cert.DNSNames) > 0 {
+		t.Set(TagForPeerCertDNSNames, strings.Join(cert.DNSNames, ","))
+	}
+	if uris := http_peercert.URIs(cert); len(uris) > 0 {
+		t.Set(TagForPeerCertURIs, strings.Join(uris, ","))
+	}
+	if id, ok := http_peercert.SPIFFEID(cert); ok {
+		t.Set(TagForPeerSpiffeID, id)
+	}
+}
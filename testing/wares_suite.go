@@ -12,6 +12,7 @@ import (
 	"runtime"
 
 	"crypto/tls"
+	"crypto/x509"
 	"net/http"
 
 	"github.com/go-chi/chi"
@@ -41,6 +42,11 @@ type WaresTestSuite struct {
 	ServerMiddleware        []httpwares.Middleware
 	ClientTripperware       []httpwares.Tripperware
 
+	// ServerClientCAs, if set, makes the TLS server verify client certificates that are presented against these CAs.
+	ServerClientCAs *x509.CertPool
+	// ClientCertificates are presented by clients returned by NewClient to the TLS server.
+	ClientCertificates []tls.Certificate
+
 	Handler http.Handler
 
 	ServerListener net.Listener
@@ -58,6 +64,10 @@ func (s *WaresTestSuite) SetupSuite() {
 				path.Join(getTestingCertsPath(), "localhost.key"),
 			)
 			require.NoError(s.T(), err, "failed starting TLS config for WaresTestSuite")
+			if s.ServerClientCAs != nil {
+				tlsConf.ClientCAs = s.ServerClientCAs
+				tlsConf.ClientAuth = tls.VerifyClientCertIfGiven
+			}
 			if !s.ServerInLegacyHttp1Mode {
 				tlsConf, err = connhelpers.TlsConfigWithHttp2Enabled(tlsConf)
 			}
@@ -96,6 +106,7 @@ func (s *WaresTestSuite) NewClient() *http.Client {
 		},
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: true,
+			Certificates:       s.ClientCertificates,
 		},
 	}
 	if !s.ClientInLegacyHttp1Mode {
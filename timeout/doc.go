@@ -0,0 +1,16 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+/*
+`http_timeout` limits the time handlers have to respond to requests.
+
+Unlike `http.TimeoutHandler`, the Middleware doesn't buffer responses, and keeps the `http.Flusher`, `http.Hijacker`,
+`http.Pusher` and `http.CloseNotifier` interfaces of the `http.ResponseWriter` available to handlers. Once the timeout
+passes, the request's context is cancelled and, if the handler hasn't started writing the response yet, a 503 response
+is sent to the client. Anything the handler writes afterwards is dropped, with `http.ErrHandlerTimeout` returned from
+`Write`.
+
+Timed out requests are tagged with `http.timeout.timed_out` in `http_ctxtags`, so that they can be told apart in logs
+and metrics.
+*/
+package http_timeout
@@ -0,0 +1,60 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// +build go1.8
+
+package http_timeout_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/improbable-eng/go-httpwares/timeout"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+// http2InterfacesHandler checks that the HTTP/2 interfaces reach the handler.
+func http2InterfacesHandler(resp http.ResponseWriter, req *http.Request) {
+	pusher, isPusher := resp.(http.Pusher)
+	_, isCloseNotifier := resp.(http.CloseNotifier)
+	if !isPusher || !isCloseNotifier {
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	// The client doesn't accept pushes, which only the underlying response writer can tell.
+	if err := pusher.Push("/pushed", nil); err != http.ErrNotSupported {
+		resp.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	httpwares_testing.PingBackHandler(httpwares_testing.DefaultPingBackStatusCode).ServeHTTP(resp, req)
+}
+
+func TestTimeoutHttp2Suite(t *testing.T) {
+	s := &TimeoutHttp2Suite{
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: http.HandlerFunc(http2InterfacesHandler),
+			ServerMiddleware: []httpwares.Middleware{
+				http_timeout.Middleware(time.Hour),
+			},
+		},
+	}
+	suite.Run(t, s)
+}
+
+type TimeoutHttp2Suite struct {
+	*httpwares_testing.WaresTestSuite
+}
+
+func (s *TimeoutHttp2Suite) TestInterfacesAreKept() {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl", nil)
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	resp.Body.Close()
+	assert.Equal(s.T(), 2, resp.ProtoMajor, "the call should use HTTP/2")
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "pushing and close notification should be available")
+}
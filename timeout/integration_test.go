@@ -0,0 +1,168 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_timeout_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/tags"
+	"github.com/improbable-eng/go-httpwares/testing"
+	"github.com/improbable-eng/go-httpwares/timeout"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+const handlerTimeout = 100 * time.Millisecond
+
+// slowHandler waits for the request to be cancelled before writing, depending on the query parameters.
+type slowHandler struct {
+	mu           sync.Mutex
+	lateWriteErr error
+}
+
+func (h *slowHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	switch req.URL.Query().Get("mode") {
+	case "slow":
+		<-req.Context().Done()
+		resp.Header().Set("x-late-header", "true")
+		_, err := resp.Write([]byte("too late"))
+		h.mu.Lock()
+		h.lateWriteErr = err
+		h.mu.Unlock()
+		return
+	case "started":
+		resp.WriteHeader(http.StatusOK)
+		resp.Write([]byte("partial"))
+		resp.(http.Flusher).Flush()
+		<-req.Context().Done()
+		resp.Write([]byte(" response"))
+		return
+	case "interfaces":
+		_, isFlusher := resp.(http.Flusher)
+		_, isHijacker := resp.(http.Hijacker)
+		_, isCloseNotifier := resp.(http.CloseNotifier)
+		if !isFlusher || !isHijacker || !isCloseNotifier {
+			resp.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	httpwares_testing.PingBackHandler(httpwares_testing.DefaultPingBackStatusCode).ServeHTTP(resp, req)
+}
+
+func (h *slowHandler) lastLateWriteErr() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.lateWriteErr
+}
+
+// outcomeRecorder records what the middlewares before the timeout one see.
+type outcomeRecorder struct {
+	mu         sync.Mutex
+	timedOut   interface{}
+	statusCode int
+}
+
+func (r *outcomeRecorder) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		wrapped := httpwares.WrapResponseWriter(resp)
+		next.ServeHTTP(wrapped, req)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.timedOut = http_ctxtags.ExtractInbound(req).Values()[http_timeout.TagForTimedOut]
+		r.statusCode = wrapped.StatusCode()
+	})
+}
+
+func (r *outcomeRecorder) outcome() (interface{}, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.timedOut, r.statusCode
+}
+
+func TestTimeoutSuite(t *testing.T) {
+	handler := &slowHandler{}
+	recorder := &outcomeRecorder{}
+	s := &TimeoutSuite{
+		handler:  handler,
+		recorder: recorder,
+		WaresTestSuite: &httpwares_testing.WaresTestSuite{
+			Handler: handler,
+			ServerMiddleware: []httpwares.Middleware{
+				http_ctxtags.Middleware("my_group"),
+				recorder.middleware,
+				http_timeout.Middleware(time.Hour, http_timeout.WithGroupTimeout("my_group", handlerTimeout)),
+			},
+			ClientInLegacyHttp1Mode: true, // so that hijacking is available
+		},
+	}
+	suite.Run(t, s)
+}
+
+type TimeoutSuite struct {
+	*httpwares_testing.WaresTestSuite
+	handler  *slowHandler
+	recorder *outcomeRecorder
+}
+
+func (s *TimeoutSuite) call(mode string) (*http.Response, string) {
+	req, _ := http.NewRequest("GET", "https://something.local/someurl?mode="+mode, nil)
+	resp, err := s.NewClient().Do(req.WithContext(s.SimpleCtx()))
+	require.NoError(s.T(), err, "call shouldn't fail")
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(s.T(), err, "reading the body shouldn't fail")
+	return resp, string(body)
+}
+
+func (s *TimeoutSuite) TestFastHandlerIsNotAffected() {
+	resp, _ := s.call("fast")
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "fast handlers should respond")
+	timedOut, statusCode := s.recorder.outcome()
+	assert.Nil(s.T(), timedOut, "the request shouldn't be tagged as timed out")
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, statusCode)
+}
+
+func (s *TimeoutSuite) TestInterfacesAreKept() {
+	resp, _ := s.call("interfaces")
+	assert.Equal(s.T(), httpwares_testing.DefaultPingBackStatusCode, resp.StatusCode, "flushing, hijacking and close notification should be available")
+}
+
+func (s *TimeoutSuite) TestSlowHandlerTimesOut() {
+	start := time.Now()
+	resp, body := s.call("slow")
+	assert.True(s.T(), time.Since(start) < 10*handlerTimeout, "the group's timeout should be used")
+	assert.Equal(s.T(), http.StatusServiceUnavailable, resp.StatusCode, "the timeout response should be sent")
+	assert.Equal(s.T(), "handler timed out\n", body)
+	assert.Empty(s.T(), resp.Header.Get("x-late-header"), "headers set after the timeout should be dropped")
+	assert.Equal(s.T(), http.ErrHandlerTimeout, s.handler.lastLateWriteErr(), "late writes should fail")
+	timedOut, statusCode := s.recorder.outcome()
+	assert.Equal(s.T(), true, timedOut, "the request should be tagged as timed out")
+	assert.Equal(s.T(), http.StatusServiceUnavailable, statusCode, "wrapping middlewares should see the timeout status")
+}
+
+func (s *TimeoutSuite) TestStartedResponseIsNotReplaced() {
+	resp, body := s.call("started")
+	assert.Equal(s.T(), http.StatusOK, resp.StatusCode, "the status written by the handler should be kept")
+	assert.Equal(s.T(), "partial", body, "writes after the timeout should be dropped")
+	timedOut, _ := s.recorder.outcome()
+	assert.Equal(s.T(), true, timedOut, "the request should be tagged as timed out")
+}
+
+func TestCustomTimeoutResponse(t *testing.T) {
+	handler := http_timeout.Middleware(handlerTimeout, http_timeout.WithTimeoutResponse(http.StatusGatewayTimeout, "upstream too slow"))(
+		http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			<-req.Context().Done()
+		}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/someurl", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, recorder.Code)
+	assert.Equal(t, "upstream too slow", recorder.Body.String())
+}
@@ -0,0 +1,52 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_timeout
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/improbable-eng/go-httpwares/tags"
+)
+
+// TagForTimedOut is the inbound tag set to true on requests whose handler timed out.
+const TagForTimedOut = "http.timeout.timed_out"
+
+// Middleware returns a http.Handler middleware that times out handlers after the given timeout.
+//
+// The timeout can be customized per handler group using `WithGroupTimeout`. The handler keeps running after timing out,
+// and this middleware only returns once it does, so handlers need to respect the cancellation of the request's
+// context to release their resources.
+func Middleware(timeout time.Duration, opts ...Option) httpwares.Middleware {
+	o := evaluateOptions(opts)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			tags := http_ctxtags.ExtractInbound(req)
+			thisTimeout := timeout
+			if group, ok := tags.Values()[http_ctxtags.TagForHandlerGroup].(string); ok {
+				if groupTimeout, ok := o.groupTimeouts[group]; ok {
+					thisTimeout = groupTimeout
+				}
+			}
+			if thisTimeout <= 0 {
+				next.ServeHTTP(resp, req)
+				return
+			}
+			ctx, cancel := context.WithCancel(req.Context())
+			defer cancel()
+			w := newTimeoutWriter(httpwares.WrapResponseWriter(resp))
+			timer := time.AfterFunc(thisTimeout, func() {
+				w.timeout(o.statusCode, o.body)
+				cancel()
+			})
+			next.ServeHTTP(w.forHandler(), req.WithContext(ctx))
+			timer.Stop()
+			if w.hasTimedOut() {
+				tags.Set(TagForTimedOut, true)
+			}
+		})
+	}
+}
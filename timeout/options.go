@@ -0,0 +1,54 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_timeout
+
+import (
+	"net/http"
+	"time"
+)
+
+var (
+	defaultOptions = &options{
+		groupTimeouts: make(map[string]time.Duration),
+		statusCode:    http.StatusServiceUnavailable,
+		body:          []byte("handler timed out\n"),
+	}
+)
+
+type options struct {
+	groupTimeouts map[string]time.Duration
+	statusCode    int
+	body          []byte
+}
+
+func evaluateOptions(opts []Option) *options {
+	optCopy := &options{}
+	*optCopy = *defaultOptions
+	optCopy.groupTimeouts = make(map[string]time.Duration)
+	for _, o := range opts {
+		o(optCopy)
+	}
+	return optCopy
+}
+
+type Option func(*options)
+
+// WithGroupTimeout sets the timeout for handlers in the given handler group, instead of the default one.
+//
+// The handler group is set by `http_ctxtags.Middleware`, which needs to be placed before this one in the chain.
+func WithGroupTimeout(handlerGroup string, timeout time.Duration) Option {
+	return func(o *options) {
+		o.groupTimeouts[handlerGroup] = timeout
+	}
+}
+
+// WithTimeoutResponse customizes the response sent when a handler times out. The default is a 503 with a short text.
+//
+// A 504 (`http.StatusGatewayTimeout`) is a better fit for handlers that mostly proxy requests to other services.
+func WithTimeoutResponse(statusCode int, body string) Option {
+	return func(o *options) {
+		o.statusCode = statusCode
+		o.body = []byte(body)
+	}
+}
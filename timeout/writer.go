@@ -0,0 +1,128 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+package http_timeout
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/improbable-eng/go-httpwares"
+)
+
+// timeoutWriter guards the response against concurrent writes by the handler and the timeout.
+//
+// The handler gets its own header map, which is only copied to the response when the headers are written, so that
+// the handler can keep modifying it after timing out.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	resp        httpwares.WrappedResponseWriter
+	header      http.Header
+	wroteHeader bool
+	hijacked    bool
+	timedOut    bool
+}
+
+func newTimeoutWriter(resp httpwares.WrappedResponseWriter) *timeoutWriter {
+	return &timeoutWriter{resp: resp, header: make(http.Header)}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writeHeaderLocked(code)
+}
+
+func (w *timeoutWriter) writeHeaderLocked(code int) {
+	if w.timedOut || w.wroteHeader || w.hijacked {
+		return
+	}
+	w.wroteHeader = true
+	dst := w.resp.Header()
+	for k, v := range w.header {
+		dst[k] = v
+	}
+	w.resp.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(buf []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+	w.writeHeaderLocked(http.StatusOK)
+	return w.resp.Write(buf)
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.hijacked {
+		return
+	}
+	w.writeHeaderLocked(http.StatusOK)
+	if flusher, ok := w.resp.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// timeout marks the response as timed out, and sends the timeout response if nothing was written yet.
+func (w *timeoutWriter) timeout(statusCode int, body []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.hijacked {
+		return
+	}
+	w.timedOut = true
+	if w.wroteHeader || w.resp.StatusCode() != 0 {
+		return
+	}
+	h := w.resp.Header()
+	h.Set("Content-Type", "text/plain; charset=utf-8")
+	h.Set("Content-Length", strconv.Itoa(len(body)))
+	w.resp.WriteHeader(statusCode)
+	w.resp.Write(body)
+	if flusher, ok := w.resp.(http.Flusher); ok {
+		flusher.Flush() // the handler may still take a while to return, send the response now
+	}
+}
+
+// hasTimedOut waits for a running timeout to finish, and returns whether the response timed out.
+func (w *timeoutWriter) hasTimedOut() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.timedOut
+}
+
+// http1TimeoutWriter exposes the `http.Hijacker` and `http.CloseNotifier` of HTTP/1.1 responses.
+type http1TimeoutWriter struct {
+	*timeoutWriter
+}
+
+func (w *http1TimeoutWriter) CloseNotify() <-chan bool {
+	return w.resp.(http.CloseNotifier).CloseNotify()
+}
+
+func (w *http1TimeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return nil, nil, http.ErrHandlerTimeout
+	}
+	conn, rw, err := w.resp.(http.Hijacker).Hijack()
+	if err == nil {
+		w.hijacked = true
+	}
+	return conn, rw, err
+}
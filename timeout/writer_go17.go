@@ -0,0 +1,16 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// +build go1.7,!go1.8
+
+package http_timeout
+
+import "net/http"
+
+// forHandler returns the writer to pass to the handler, keeping the interfaces of the original response writer.
+func (w *timeoutWriter) forHandler() http.ResponseWriter {
+	if _, isHijacker := w.resp.(http.Hijacker); isHijacker {
+		return &http1TimeoutWriter{w}
+	}
+	return w
+}
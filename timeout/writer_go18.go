@@ -0,0 +1,38 @@
+// Copyright 2017 Michal Witkowski. All Rights Reserved.
+// See LICENSE for licensing terms.
+
+// +build go1.8
+
+package http_timeout
+
+import "net/http"
+
+// forHandler returns the writer to pass to the handler, keeping the interfaces of the original response writer.
+func (w *timeoutWriter) forHandler() http.ResponseWriter {
+	_, isHijacker := w.resp.(http.Hijacker)
+	_, isPusher := w.resp.(http.Pusher)
+	if isHijacker {
+		return &http1TimeoutWriter{w}
+	} else if isPusher {
+		return &http2TimeoutWriter{w}
+	}
+	return w
+}
+
+// http2TimeoutWriter exposes the `http.Pusher` and `http.CloseNotifier` of HTTP/2 responses.
+type http2TimeoutWriter struct {
+	*timeoutWriter
+}
+
+func (w *http2TimeoutWriter) CloseNotify() <-chan bool {
+	return w.resp.(http.CloseNotifier).CloseNotify()
+}
+
+func (w *http2TimeoutWriter) Push(target string, opts *http.PushOptions) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return http.ErrHandlerTimeout
+	}
+	return w.resp.(http.Pusher).Push(target, opts)
+}
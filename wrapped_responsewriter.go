@@ -31,6 +31,62 @@ type WrappedResponseWriter interface {
 	ObserveWrite(func(t WrappedResponseWriter, buf []byte, n int, err error))
 }
 
+// ResponseFilter transforms the response written by a handler, e.g. compresses or buffers it, before it is written to
+// a WrappedResponseWriter. See `WrapResponseWriterWithFilter`.
+type ResponseFilter interface {
+	// WriteHeader is called with the status code written by the handler, once, and always before Write and Flush.
+	WriteHeader(code int)
+	// Write is called with the data written by the handler.
+	Write(buf []byte) (int, error)
+	// Flush is called when the handler flushes the response.
+	Flush()
+}
+
+// EncodingResponseFilter is implemented by ResponseFilters that encode the response, e.g. compress it.
+type EncodingResponseFilter interface {
+	ResponseFilter
+	// Encoded reports whether the response is written encoded to the WrappedResponseWriter.
+	Encoded() bool
+}
+
+// WrapResponseWriterWithFilter returns a WrappedResponseWriter for the handler, whose header and data are passed to the
+// filter instead of w. The filter is expected to write the response to w once it is done with it.
+//
+// The returned writer keeps the `http.Flusher`, `http.Hijacker`, `http.Pusher` and `http.CloseNotifier` of w, and its
+// StatusCode and MessageLength describe the response as written by the handler. If the filter is an
+// `EncodingResponseFilter`, `DecodedMessageLength(w)` reports the size of the response before it was encoded.
+func WrapResponseWriterWithFilter(w WrappedResponseWriter, filter ResponseFilter) WrappedResponseWriter {
+	wrapped := &wrappedResponseWriter{ResponseWriter: w, filter: filter}
+	if parent, ok := w.(interface {
+		base() *wrappedResponseWriter
+	}); ok {
+		if _, ok := filter.(EncodingResponseFilter); ok {
+			parent.base().encodedBy = wrapped
+		}
+	}
+	switch w.(type) {
+	case *http1WrappedResponseWriter:
+		return &http1WrappedResponseWriter{wrapped}
+	case *http2WrappedResponseWriter:
+		return &http2WrappedResponseWriter{wrapped}
+	}
+	return wrapped
+}
+
+// DecodedMessageLength returns the size of the HTTP Response Message written to w before it was encoded by an
+// `EncodingResponseFilter` (e.g. compressed), or its MessageLength() if it wasn't encoded.
+//
+// Only the WrappedResponseWriters of `WrapResponseWriter` know about encodings, other implementations of the interface
+// report their MessageLength().
+func DecodedMessageLength(w WrappedResponseWriter) int {
+	if wrapped, ok := w.(interface {
+		base() *wrappedResponseWriter
+	}); ok {
+		return wrapped.base().decodedMessageLength()
+	}
+	return w.MessageLength()
+}
+
 // wrappedResponseWriter implements http.ResponseWriter without extensions.
 type wrappedResponseWriter struct {
 	http.ResponseWriter
@@ -39,6 +95,14 @@ type wrappedResponseWriter struct {
 	wroteHdr       bool
 	observerHeader []func(t WrappedResponseWriter, code int)
 	observerWrite  []func(t WrappedResponseWriter, buf []byte, n int, err error)
+	// filter, if set, receives the response instead of ResponseWriter.
+	filter ResponseFilter
+	// encodedBy is the writer of the handler, if an EncodingResponseFilter writes its response to this one.
+	encodedBy *wrappedResponseWriter
+}
+
+func (w *wrappedResponseWriter) base() *wrappedResponseWriter {
+	return w
 }
 
 func (w *wrappedResponseWriter) Header() http.Header {
@@ -57,7 +121,11 @@ func (w *wrappedResponseWriter) WriteHeader(code int) {
 	if !w.wroteHdr {
 		w.wroteHdr = true
 		w.code = code
-		w.ResponseWriter.WriteHeader(code)
+		if w.filter != nil {
+			w.filter.WriteHeader(code)
+		} else {
+			w.ResponseWriter.WriteHeader(code)
+		}
 		for _, o := range w.observerHeader {
 			o(w, code)
 		}
@@ -66,7 +134,13 @@ func (w *wrappedResponseWriter) WriteHeader(code int) {
 
 func (w *wrappedResponseWriter) Write(buf []byte) (int, error) {
 	w.WriteHeader(http.StatusOK) // double writes are ignored.
-	n, err := w.ResponseWriter.Write(buf)
+	var n int
+	var err error
+	if w.filter != nil {
+		n, err = w.filter.Write(buf)
+	} else {
+		n, err = w.ResponseWriter.Write(buf)
+	}
 	for _, o := range w.observerWrite {
 		o(w, buf, n, err)
 	}
@@ -82,10 +156,22 @@ func (w *wrappedResponseWriter) MessageLength() int {
 	return w.bytes
 }
 
+func (w *wrappedResponseWriter) decodedMessageLength() int {
+	if w.encodedBy != nil && w.encodedBy.filter.(EncodingResponseFilter).Encoded() {
+		return w.encodedBy.decodedMessageLength()
+	}
+	return w.bytes
+}
+
 func (w *wrappedResponseWriter) CloseNotify() <-chan bool {
 	return w.ResponseWriter.(http.CloseNotifier).CloseNotify()
 }
 
 func (w *wrappedResponseWriter) Flush() {
+	if w.filter != nil {
+		w.WriteHeader(http.StatusOK) // flushing sends the headers, so the filter gets them first.
+		w.filter.Flush()
+		return
+	}
 	w.ResponseWriter.(http.Flusher).Flush()
 }
@@ -34,7 +34,7 @@ type http2WrappedResponseWriter struct {
 }
 
 func (w *http2WrappedResponseWriter) Flush() {
-	w.wrappedResponseWriter.ResponseWriter.(http.Flusher).Flush()
+	w.wrappedResponseWriter.Flush()
 }
 
 func (w *http2WrappedResponseWriter) CloseNotify() <-chan bool {
@@ -46,7 +46,7 @@ type http1WrappedResponseWriter struct {
 }
 
 func (w *http1WrappedResponseWriter) Flush() {
-	w.wrappedResponseWriter.ResponseWriter.(http.Flusher).Flush()
+	w.wrappedResponseWriter.Flush()
 }
 
 func (w *http1WrappedResponseWriter) CloseNotify() <-chan bool {
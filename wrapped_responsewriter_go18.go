@@ -35,7 +35,7 @@ type http2WrappedResponseWriter struct {
 }
 
 func (w *http2WrappedResponseWriter) Flush() {
-	w.wrappedResponseWriter.ResponseWriter.(http.Flusher).Flush()
+	w.wrappedResponseWriter.Flush()
 }
 
 func (w *http2WrappedResponseWriter) CloseNotify() <-chan bool {
@@ -51,7 +51,7 @@ type http1WrappedResponseWriter struct {
 }
 
 func (w *http1WrappedResponseWriter) Flush() {
-	w.wrappedResponseWriter.ResponseWriter.(http.Flusher).Flush()
+	w.wrappedResponseWriter.Flush()
 }
 
 func (w *http1WrappedResponseWriter) CloseNotify() <-chan bool {
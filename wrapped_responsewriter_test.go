@@ -0,0 +1,80 @@
+package httpwares_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/improbable-eng/go-httpwares"
+	"github.com/stretchr/testify/assert"
+)
+
+// halvingFilter writes every other byte of the response, pretending to encode it.
+type halvingFilter struct {
+	resp    httpwares.WrappedResponseWriter
+	calls   []string
+	flushed bool
+}
+
+func (f *halvingFilter) WriteHeader(code int) {
+	f.calls = append(f.calls, "WriteHeader")
+	f.resp.WriteHeader(code)
+}
+
+func (f *halvingFilter) Write(buf []byte) (int, error) {
+	half := make([]byte, 0, len(buf)/2)
+	for i := 0; i < len(buf); i += 2 {
+		half = append(half, buf[i])
+	}
+	if _, err := f.resp.Write(half); err != nil {
+		return 0, err
+	}
+	return len(buf), nil
+}
+
+func (f *halvingFilter) Flush() {
+	f.calls = append(f.calls, "Flush")
+	f.flushed = true
+}
+
+func (f *halvingFilter) Encoded() bool {
+	return true
+}
+
+func TestWrapResponseWriterWithFilter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	wrapped := httpwares.WrapResponseWriter(recorder)
+	filter := &halvingFilter{resp: wrapped}
+	filtered := httpwares.WrapResponseWriterWithFilter(wrapped, filter)
+	filtered.WriteHeader(http.StatusCreated)
+	filtered.Write([]byte("aabbccdd"))
+	filtered.(http.Flusher).Flush()
+
+	assert.Equal(t, http.StatusCreated, recorder.Code, "the filter should write the header")
+	assert.Equal(t, "abcd", recorder.Body.String(), "the filter should write the data")
+	assert.True(t, filter.flushed, "flushes should be passed to the filter")
+	assert.False(t, recorder.Flushed, "flushing is up to the filter")
+	assert.Equal(t, 8, filtered.MessageLength(), "the filtered writer should report the size written by the handler")
+	assert.Equal(t, 4, wrapped.MessageLength(), "the parent should report the size sent to the client")
+	assert.Equal(t, 8, httpwares.DecodedMessageLength(wrapped), "the parent should report the size before encoding")
+}
+
+func TestWrappedResponseWriterDecodedMessageLengthWithoutFilter(t *testing.T) {
+	wrapped := httpwares.WrapResponseWriter(httptest.NewRecorder())
+	wrapped.Write(bytes.Repeat([]byte("a"), 10))
+	assert.Equal(t, 10, httpwares.DecodedMessageLength(wrapped), "unencoded responses should have the same decoded size")
+}
+
+func TestWrapResponseWriterWithFilterWritesHeaderBeforeFlushing(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	wrapped := httpwares.WrapResponseWriter(recorder)
+	filter := &halvingFilter{resp: wrapped}
+	filtered := httpwares.WrapResponseWriterWithFilter(wrapped, filter)
+	filtered.(http.Flusher).Flush()
+	filtered.Write([]byte("aabb"))
+
+	assert.Equal(t, []string{"WriteHeader", "Flush"}, filter.calls, "the filter should get the header once, before the flush")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ab", recorder.Body.String())
+}